@@ -56,6 +56,10 @@ class SocketServer {
         logger.error("❌ Missing quiz_id");
         return;
       }
+      if (!this.canPlay(socket, quiz_id)) {
+        socket.emit("error", { message: "Guests can only join their own quiz" });
+        return;
+      }
       socket.join(quiz_id);
       logger.info(`✅ User ${socket.id} joined quiz: ${quiz_id}`);
     });
@@ -63,6 +67,9 @@ class SocketServer {
 
   handleUpdateScore(socket) {
     socket.on("user_online", async ({ quiz_id }) => {
+      if (!this.canPlay(socket, quiz_id)) {
+        return;
+      }
       const result = await getUserQuiz(quiz_id, socket.user.user_uuid);
       if (result) {
         socket.to(quiz_id).emit("update_leaderboard", {
@@ -80,6 +87,10 @@ class SocketServer {
         socket.emit("error", { message: "Invalid payload or user session" });
         return;
       }
+      if (!this.canPlay(socket, quiz_id)) {
+        socket.emit("error", { message: "Guests can only play their own quiz" });
+        return;
+      }
 
       try {
         const { success, result, correct_answers, is_shoudl_update } = await calculateScore(
@@ -111,6 +122,11 @@ class SocketServer {
     });
   }
 
+  // canPlay limits guest sockets to the quiz their join code was issued for
+  canPlay(socket, quiz_id) {
+    return !socket.user?.guest || socket.user.quiz_uuid === quiz_id;
+  }
+
   handleDisconnect(socket) {
     socket.on("disconnect", () => {
       logger.info(`❌ User disconnected: ${socket.id}`);
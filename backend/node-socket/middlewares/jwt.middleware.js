@@ -1,4 +1,4 @@
-const jwt = require("jsonwebtoken");
+const { verifyToken } = require("../utils/jwks");
 const RedisClient = require("../config/redis");
 const logger = require("../utils/logger"); // Import centralized logger
 
@@ -18,12 +18,17 @@ const authSocket = async (socket, next) => {
     // Verify JWT token
     let decoded;
     try {
-      decoded = jwt.verify(token, process.env.JWT_SECRET);
+      decoded = await verifyToken(token);
     } catch (err) {
       logger.error(`Authentication error: Invalid token - ${err.message}`);
       return next(new Error("Authentication error: Invalid token"));
     }
 
+    // Guests may only play the quiz their join code was issued for
+    if (decoded.guest) {
+      return await authGuest(socket, decoded, next);
+    }
+
     const { user_id, session_uuid, user_uuid, fullname } = decoded;
 
     // Validate token claims
@@ -39,13 +44,13 @@ const authSocket = async (socket, next) => {
       return next(new Error("Authentication error: Redis unavailable"));
     }
 
-    const storedSessionUUID = await redisClient.get(`user:${user_id}`);
-    if (!storedSessionUUID) {
+    const storedSession = await redisClient.get(`session:${session_uuid.trim()}`);
+    if (!storedSession) {
       logger.error("Authentication error: Session not found in Redis");
       return next(new Error("Authentication error: Session not found"));
     }
 
-    if (storedSessionUUID.replaceAll('"', "") !== session_uuid.trim()) {
+    if (JSON.parse(storedSession).user_id !== user_id) {
       logger.error("Authentication error: Session mismatch");
       return next(new Error("Authentication error: Session mismatch"));
     }
@@ -59,4 +64,24 @@ const authSocket = async (socket, next) => {
   }
 };
 
+/**
+ * Validates a guest token against its guest session in Redis.
+ */
+const authGuest = async (socket, decoded, next) => {
+  const { user_uuid, fullname, quiz_uuid } = decoded;
+  if (!user_uuid || !quiz_uuid) {
+    logger.error("Authentication error: Missing user_uuid or quiz_uuid in guest token");
+    return next(new Error("Authentication error: Invalid token claims"));
+  }
+
+  const storedGuest = await RedisClient.getClient().get(`guest:${user_uuid}`);
+  if (!storedGuest || JSON.parse(storedGuest).quiz_uuid !== quiz_uuid) {
+    logger.error("Authentication error: Guest session not found");
+    return next(new Error("Authentication error: Guest session not found"));
+  }
+
+  socket.user = { guest: true, user_uuid, fullname, quiz_uuid };
+  next();
+};
+
 module.exports = authSocket;
@@ -0,0 +1,96 @@
+const crypto = require("crypto");
+const jwt = require("jsonwebtoken");
+const logger = require("./logger");
+
+// Minimum time between two JWKS downloads triggered by an unknown kid
+const REFETCH_BACKOFF_MS = 10 * 1000;
+
+const keys = new Map();
+let lastFetch = 0;
+
+/**
+ * Downloads the quiz-api key set and caches the public keys by kid.
+ */
+const fetchKeys = async () => {
+  lastFetch = Date.now();
+  const response = await fetch(process.env.JWKS_URL);
+  if (!response.ok) {
+    throw new Error(`Failed to fetch JWKS: ${response.status}`);
+  }
+
+  const { keys: jwks = [] } = await response.json();
+  keys.clear();
+  for (const jwk of jwks) {
+    try {
+      keys.set(jwk.kid, { alg: jwk.alg, key: crypto.createPublicKey({ key: jwk, format: "jwk" }) });
+    } catch (err) {
+      logger.error(`Skipping invalid JWK ${jwk.kid}: ${err.message}`);
+    }
+  }
+};
+
+const getKey = async (kid) => {
+  if (!keys.has(kid) && Date.now() - lastFetch > REFETCH_BACKOFF_MS) {
+    await fetchKeys();
+  }
+  return keys.get(kid);
+};
+
+const decodeSegment = (segment) => JSON.parse(Buffer.from(segment, "base64url").toString());
+
+/**
+ * Legacy HS256 tokens are only accepted with JWT_SECRET and before JWT_LEGACY_ACCEPT_UNTIL (an ISO 8601 time).
+ */
+const legacyAccepted = () => {
+  const until = Date.parse(process.env.JWT_LEGACY_ACCEPT_UNTIL || "");
+  return Boolean(process.env.JWT_SECRET) && Number.isFinite(until) && Date.now() < until;
+};
+
+/**
+ * Verifies an access token signed by quiz-api with RS256 or EdDSA, looking the key up by kid.
+ * Tokens without a kid are legacy HS256 tokens, accepted only during the migration window.
+ */
+const verifyToken = async (token) => {
+  const parts = token.split(".");
+  if (parts.length !== 3) {
+    throw new Error("malformed token");
+  }
+
+  const header = decodeSegment(parts[0]);
+  if (!header.kid) {
+    if (!legacyAccepted()) {
+      throw new Error("token has no key id");
+    }
+    return jwt.verify(token, process.env.JWT_SECRET, { algorithms: ["HS256"] });
+  }
+
+  const entry = await getKey(header.kid);
+  if (!entry || entry.alg !== header.alg) {
+    throw new Error("unknown signing key");
+  }
+
+  const data = Buffer.from(`${parts[0]}.${parts[1]}`);
+  const signature = Buffer.from(parts[2], "base64url");
+  let valid;
+  switch (header.alg) {
+    case "RS256":
+      valid = crypto.verify("sha256", data, entry.key, signature);
+      break;
+    case "EdDSA":
+      valid = crypto.verify(null, data, entry.key, signature);
+      break;
+    default:
+      throw new Error(`unsupported algorithm ${header.alg}`);
+  }
+  if (!valid) {
+    throw new Error("invalid signature");
+  }
+
+  const payload = decodeSegment(parts[1]);
+  if (typeof payload.exp !== "number" || payload.exp * 1000 <= Date.now()) {
+    throw new Error("token expired");
+  }
+  return payload;
+};
+
+module.exports = { verifyToken };
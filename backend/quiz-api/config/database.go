@@ -17,6 +17,12 @@ var MIGRATE_MODELS = []interface{}{
 	&models.Quiz{},
 	&models.Question{},
 	&models.Answer{},
+	&models.QuizCollaborator{},
+	&models.UserIdentity{},
+	&models.APIKey{},
+	&models.SigningKey{},
+	&models.GuestPlayer{},
+	&models.RecoveryCode{},
 }
 
 func InitDB() *gorm.DB {
@@ -44,6 +50,8 @@ func InitDB() *gorm.DB {
 
 func autoMigrate(db *gorm.DB) {
 	db.AutoMigrate(MIGRATE_MODELS...)
+	// Accounts created before roles existed keep their admin rights
+	db.Model(&models.User{}).Where("is_admin = ? AND role <> ?", true, models.RoleAdmin).Update("role", models.RoleAdmin)
 	createAdminUser(db)
 }
 
@@ -68,6 +76,7 @@ func createAdminUser(db *gorm.DB) {
 		Password: string(hashedPassword),
 		FullName: "Administrator",
 		IsAdmin:  true,
+		Role:     models.RoleAdmin,
 	}
 
 	// Save the admin user to the database
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+// OIDCConfig holds the OpenID Connect client settings. Single sign-on is disabled when Issuer is empty.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+func NewOIDCConfig() *OIDCConfig {
+	godotenv.Load()
+
+	scopes := []string{"openid", "profile", "email"}
+	if value := os.Getenv("OIDC_SCOPES"); value != "" {
+		scopes = strings.Fields(strings.ReplaceAll(value, ",", " "))
+	}
+
+	return &OIDCConfig{
+		Issuer:       strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		Scopes:       scopes,
+	}
+}
+
+func (c *OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != "" && c.RedirectURL != ""
+}
@@ -74,10 +74,50 @@ func (r *RedisClient) Exists(ctx context.Context, key string) (bool, error) {
 	return result > 0, nil
 }
 
-func (r *RedisClient) Delete(ctx context.Context, key string) error {
-	return r.Client.Del(ctx, key).Err()
+func (r *RedisClient) Delete(ctx context.Context, keys ...string) error {
+	return r.Client.Del(ctx, keys...).Err()
 }
 
 func (r *RedisClient) Close() error {
 	return r.Client.Close()
 }
+
+func (r *RedisClient) SetNX(ctx context.Context, key string, value interface{}, expiration time.Duration) (bool, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	return r.Client.SetNX(ctx, key, data, expiration).Result()
+}
+
+func (r *RedisClient) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return r.Client.Expire(ctx, key, expiration).Err()
+}
+
+func (r *RedisClient) SAdd(ctx context.Context, key string, members ...interface{}) error {
+	return r.Client.SAdd(ctx, key, members...).Err()
+}
+
+func (r *RedisClient) SMembers(ctx context.Context, key string) ([]string, error) {
+	return r.Client.SMembers(ctx, key).Result()
+}
+
+func (r *RedisClient) SRem(ctx context.Context, key string, members ...interface{}) error {
+	return r.Client.SRem(ctx, key, members...).Err()
+}
+
+func (r *RedisClient) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return r.Client.TTL(ctx, key).Result()
+}
+
+func (r *RedisClient) Incr(ctx context.Context, key string, expiration time.Duration) (int64, error) {
+	count, err := r.Client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Start the expiry window on the first increment only
+	if count == 1 {
+		err = r.Client.Expire(ctx, key, expiration).Err()
+	}
+	return count, err
+}
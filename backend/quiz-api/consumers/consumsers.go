@@ -1,12 +1,12 @@
 package consumers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"quiz-api/services"
-	"quiz-api/utils"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -15,13 +15,13 @@ import (
 // KafkaHandlerFunc is the type for Kafka message processing functions
 type KafkaConsumer func(key string, value string)
 
-func RegisterKafkaConsumers(logger *logrus.Logger, quizExportSerice *services.QuizExportService) map[string]func(key, value string) {
+func RegisterKafkaConsumers(logger *logrus.Logger, quizExportSerice *services.QuizExportService, notificationService *services.NotificationService) map[string]func(key, value string) {
 	return map[string]func(key, value string){
 		"quiz_export": func(key string, value string) {
-			quizExport(logger, quizExportSerice, key, value)
+			quizExport(logger, quizExportSerice, notificationService, key, value)
 		},
 		"revoke_quiz": func(key string, value string) {
-			revokeQuiz(logger, quizExportSerice, key, value)
+			revokeQuiz(logger, quizExportSerice, notificationService, key, value)
 		},
 		"user_quiz_export": func(key, value string) {
 			exportUserQuizToFile(logger, key, value)
@@ -29,27 +29,49 @@ func RegisterKafkaConsumers(logger *logrus.Logger, quizExportSerice *services.Qu
 	}
 }
 
-func quizExport(logger *logrus.Logger, quizExportSerice *services.QuizExportService, key string, value string) {
-	fmt.Println("Consumed message:", key, value)
-	err, title := quizExportSerice.ExportQuiz(key, value)
+// quizStatusPayload tells the requesting user how a publish or unpublish ended
+type quizStatusPayload struct {
+	QuizUUID string `json:"quiz_uuid"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+}
+
+// notifyQuizStatus sends the result to the user who requested it; the message value is their UUID
+func notifyQuizStatus(logger *logrus.Logger, notificationService *services.NotificationService, eventType, action, quizUUID, userUUID, title string, err error) {
+	payload := quizStatusPayload{
+		QuizUUID: quizUUID,
+		Title:    title,
+		Status:   "completed",
+		Message:  fmt.Sprintf("%s Quiz %s Completed", action, title),
+	}
 	if err != nil {
-		utils.SendNotification(value, fmt.Sprintf("Publish Quiz %s Falied", title))
-	} else {
-		utils.SendNotification(value, fmt.Sprintf("Publish Quiz %s Completed", title))
+		payload.Status = "failed"
+		payload.Message = fmt.Sprintf("%s Quiz %s Failed", action, title)
+	}
+
+	if err := notificationService.NotifyUser(context.Background(), userUUID, eventType, payload); err != nil {
+		logger.WithFields(logrus.Fields{
+			"quiz_uuid": quizUUID,
+			"user_uuid": userUUID,
+			"error":     err.Error(),
+		}).Error("Failed to send notification")
 	}
+}
+
+func quizExport(logger *logrus.Logger, quizExportSerice *services.QuizExportService, notificationService *services.NotificationService, key string, value string) {
+	fmt.Println("Consumed message:", key, value)
+	err, title := quizExportSerice.ExportQuiz(key, value)
+	notifyQuizStatus(logger, notificationService, "quiz.export", "Publish", key, value, title, err)
 	logger.WithFields(logrus.Fields{
 		"key":   key,
 		"value": value,
 	}).Info("Message consumed")
 }
 
-func revokeQuiz(logger *logrus.Logger, quizExportSerice *services.QuizExportService, key string, value string) {
+func revokeQuiz(logger *logrus.Logger, quizExportSerice *services.QuizExportService, notificationService *services.NotificationService, key string, value string) {
 	title, err := quizExportSerice.RevokeQuiz(key, value)
-	if err != nil {
-		utils.SendNotification(value, fmt.Sprintf("Unpublish Quiz %s Falied", title))
-	} else {
-		utils.SendNotification(value, fmt.Sprintf("Unpublish Quiz %s Completed", title))
-	}
+	notifyQuizStatus(logger, notificationService, "quiz.revoke", "Unpublish", key, value, title, err)
 	fmt.Println("Consumed message:", key, value)
 	logger.WithFields(logrus.Fields{
 		"key":   key,
@@ -1,6 +1,7 @@
 package containers
 
 import (
+	"context"
 	"log"
 	"quiz-api/config"
 	"quiz-api/controllers"
@@ -19,16 +20,40 @@ func BuildContainer() *dig.Container {
 	container.Provide(config.NewScyllaConfig)
 	container.Provide(config.NewScyllaDB)
 	container.Provide(config.NewRedisClient)
+	container.Provide(config.NewOIDCConfig)
+
+	container.Provide(repositories.NewSigningKeyRepository)
+	container.Provide(services.NewSigningKeyService)
+	container.Provide(controllers.NewJWKSController)
+	container.Provide(services.NewSessionService)
+	container.Provide(services.NewLoginGuardService)
+	container.Provide(services.NewRateLimitService)
+	container.Provide(repositories.NewAPIKeyRepository)
+	container.Provide(services.NewAPIKeyService)
 
 	container.Provide(middlewares.NewLoggingMiddleware)
 	container.Provide(middlewares.NewAdminMiddleware)
 	container.Provide(middlewares.NewJWTMiddleware)
+	container.Provide(middlewares.NewPolicyMiddleware)
+	container.Provide(middlewares.NewRateLimitMiddleware)
 
 	container.Provide(repositories.NewScyllaDBRepository)
 
 	container.Provide(repositories.NewUserRepository)
+	container.Provide(services.NewTokenService)
+	container.Provide(services.NewEmailTokenService)
+	container.Provide(services.NewMailer)
+	container.Provide(services.NewTwoFactorService)
 	container.Provide(services.NewUserService)
 	container.Provide(controllers.NewUserController)
+	container.Provide(services.NewOIDCService)
+	container.Provide(controllers.NewOIDCController)
+	container.Provide(controllers.NewTwoFactorController)
+	container.Provide(services.NewUserAdminService)
+	container.Provide(controllers.NewUserAdminController)
+	container.Provide(controllers.NewAPIKeyController)
+	container.Provide(services.NewPersonalDataService)
+	container.Provide(controllers.NewPersonalDataController)
 
 	container.Provide(repositories.NewQuizRepository)
 	container.Provide(services.NewQuizService)
@@ -43,7 +68,13 @@ func BuildContainer() *dig.Container {
 	container.Provide(controllers.NewAnswerController)
 
 	container.Provide(services.NewQuizExportService)
+	container.Provide(services.NewPolicyService)
+
+	container.Provide(repositories.NewGuestRepository)
+	container.Provide(services.NewGuestService)
+	container.Provide(controllers.NewGuestController)
 
+	container.Provide(services.NewNotificationService)
 	container.Provide(registry.RegisterTopics)
 	container.Provide(func(cfg services.KafkaConfig) *services.KafkaService {
 		return services.NewKafkaService(cfg, 10)
@@ -52,6 +83,17 @@ func BuildContainer() *dig.Container {
 	return container
 }
 
+// RunKeyRotation keeps the token signing keys fresh and rotates them on schedule
+func RunKeyRotation(container *dig.Container) {
+	err := container.Invoke(func(signingKeyService *services.SigningKeyService) {
+		signingKeyService.Run(context.Background())
+	})
+
+	if err != nil {
+		log.Fatalf("Failed to start key rotation: %v", err)
+	}
+}
+
 func RunKafkaConsumer(container *dig.Container) {
 	err := container.Invoke(func(kafkaService *services.KafkaService, cfg services.KafkaConfig) {
 		defer kafkaService.Close()
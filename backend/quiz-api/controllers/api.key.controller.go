@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"quiz-api/dto"
+	"quiz-api/models"
+	"quiz-api/services"
+	"quiz-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController handles API key management for administrators
+type APIKeyController struct {
+	apiKeyService *services.APIKeyService
+}
+
+// NewAPIKeyController initializes a new APIKeyController
+func NewAPIKeyController(apiKeyService *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{apiKeyService: apiKeyService}
+}
+
+// CreateAPIKey issues a new API key
+func (ctrl *APIKeyController) CreateAPIKey(c *gin.Context) {
+	var request dto.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	apiKey, rawKey, err := ctrl.apiKeyService.Create(c.GetUint("userID"), request.UserUUID, request.Name, request.Scopes, request.ExpiresAt)
+	if errors.Is(err, services.ErrUserNotFound) {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	utils.SendCreated(c, dto.CreateAPIKeyResponse{APIKeyResponse: toAPIKeyResponse(apiKey), Key: rawKey})
+}
+
+// GetAPIKeys lists every API key
+func (ctrl *APIKeyController) GetAPIKeys(c *gin.Context) {
+	apiKeys, err := ctrl.apiKeyService.List()
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	data := make([]dto.APIKeyResponse, 0, len(apiKeys))
+	for i := range apiKeys {
+		data = append(data, toAPIKeyResponse(&apiKeys[i]))
+	}
+
+	utils.SendSuccess(c, data)
+}
+
+// RevokeAPIKey disables an API key immediately
+func (ctrl *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	apiKey, err := ctrl.apiKeyService.Revoke(c.Param("uuid"))
+	if errors.Is(err, services.ErrAPIKeyNotFound) {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, toAPIKeyResponse(apiKey))
+}
+
+func toAPIKeyResponse(apiKey *models.APIKey) dto.APIKeyResponse {
+	return dto.APIKeyResponse{
+		UUID:       apiKey.UUID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		UserID:     apiKey.UserID,
+		CreatedBy:  apiKey.CreatedBy,
+		Scopes:     apiKey.Scopes,
+		ExpiresAt:  apiKey.ExpiresAt,
+		RevokedAt:  apiKey.RevokedAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		LastUsedIP: apiKey.LastUsedIP,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"quiz-api/dto"
+	"quiz-api/services"
+	"quiz-api/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GuestController handles join codes and guest players
+type GuestController struct {
+	guestService *services.GuestService
+}
+
+// NewGuestController initializes a new GuestController
+func NewGuestController(guestService *services.GuestService) *GuestController {
+	return &GuestController{guestService: guestService}
+}
+
+// CreateJoinCode generates a join code for a published quiz
+func (ctrl *GuestController) CreateJoinCode(c *gin.Context) {
+	joinCode, err := ctrl.guestService.CreateJoinCode(c.Request.Context(), c.Param("uuid"), c.GetString("userUUID"))
+	if errors.Is(err, services.ErrQuizNotPublished) {
+		utils.SendError(c, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendCreated(c, joinCode)
+}
+
+// RevokeJoinCode stops new guests from joining with a code
+func (ctrl *GuestController) RevokeJoinCode(c *gin.Context) {
+	err := ctrl.guestService.RevokeJoinCode(c.Request.Context(), c.Param("code"))
+	if errors.Is(err, services.ErrInvalidJoinCode) {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, nil)
+}
+
+// Join exchanges a join code and nickname for a guest token
+func (ctrl *GuestController) Join(c *gin.Context) {
+	var request dto.GuestJoinRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendError(c, http.StatusBadRequest, "Invalid input data")
+		return
+	}
+
+	response, err := ctrl.guestService.Join(c.Request.Context(), request.Code, request.Nickname)
+	if errors.Is(err, services.ErrInvalidJoinCode) {
+		utils.SendError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, response)
+}
+
+// PurgeGuests removes guest results, optionally for one quiz and only for guests older than older_than
+func (ctrl *GuestController) PurgeGuests(c *gin.Context) {
+	olderThan := time.Duration(0)
+	if value := c.Query("older_than"); value != "" {
+		var err error
+		if olderThan, err = time.ParseDuration(value); err != nil || olderThan < 0 {
+			utils.SendError(c, http.StatusBadRequest, "older_than must be a duration such as 24h")
+			return
+		}
+	}
+
+	purged, err := ctrl.guestService.PurgeGuests(c.Request.Context(), c.Query("quiz_uuid"), time.Now().Add(-olderThan))
+	if err != nil {
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SendSuccess(c, dto.PurgeGuestsResponse{Purged: purged})
+}
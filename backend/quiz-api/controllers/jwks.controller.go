@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+	"quiz-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSController publishes the public keys that verify access tokens
+type JWKSController struct {
+	signingKeyService *services.SigningKeyService
+}
+
+// NewJWKSController initializes a new JWKSController
+func NewJWKSController(signingKeyService *services.SigningKeyService) *JWKSController {
+	return &JWKSController{signingKeyService: signingKeyService}
+}
+
+// GetJWKS returns the key set as a plain JWKS document, as verifiers expect
+func (ctrl *JWKSController) GetJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, ctrl.signingKeyService.JWKS())
+}
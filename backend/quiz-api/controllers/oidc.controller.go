@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"quiz-api/dto"
+	"quiz-api/services"
+	"quiz-api/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCController handles single sign-on through an OpenID Connect provider
+type OIDCController struct {
+	oidcService *services.OIDCService
+}
+
+// NewOIDCController initializes a new OIDCController
+func NewOIDCController(oidcService *services.OIDCService) *OIDCController {
+	return &OIDCController{oidcService: oidcService}
+}
+
+// Authorize starts a sign-on and returns the provider authorization URL
+func (ctrl *OIDCController) Authorize(c *gin.Context) {
+	ctrl.authorize(c, 0)
+}
+
+// Link starts a sign-on that links the provider account to the current user
+func (ctrl *OIDCController) Link(c *gin.Context) {
+	ctrl.authorize(c, c.GetUint("userID"))
+}
+
+// LinkCallback completes a link sign-on; it must be called with the session of the user who started it
+func (ctrl *OIDCController) LinkCallback(c *gin.Context) {
+	ctrl.callback(c, c.GetUint("userID"))
+}
+
+func (ctrl *OIDCController) authorize(c *gin.Context, linkUserID uint) {
+	authorizationURL, binding, err := ctrl.oidcService.AuthorizationURL(c.Request.Context(), linkUserID)
+	if errors.Is(err, services.ErrOIDCDisabled) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.OIDCAuthorizeResponse{AuthorizationURL: authorizationURL, Binding: binding})
+}
+
+// Callback completes a sign-on and logs the user in
+func (ctrl *OIDCController) Callback(c *gin.Context) {
+	ctrl.callback(c, 0)
+}
+
+func (ctrl *OIDCController) callback(c *gin.Context, sessionUserID uint) {
+	var request dto.OIDCCallbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, tokens, err := ctrl.oidcService.Callback(c.Request.Context(), request.Code, request.State, request.Binding, sessionUserID, c.Request.UserAgent(), c.ClientIP())
+	if respondTwoFactorChallenge(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, services.ErrOIDCDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrIdentityLinked):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.LoginResponse{
+		ID:                     user.ID,
+		UUID:                   user.UUID,
+		Username:               user.Username,
+		FullName:               user.FullName,
+		IsAdmin:                user.IsAdmin,
+		Role:                   user.Role,
+		PasswordResetRequired:  user.PasswordResetRequired,
+		TwoFactorSetupRequired: utils.TwoFactorSetupRequired(user),
+		Token:                  tokens.Token,
+		RefreshToken:           tokens.RefreshToken,
+		ExpiresIn:              tokens.ExpiresIn,
+	})
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"quiz-api/dto"
+	"quiz-api/services"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PersonalDataController handles data-subject access and deletion requests of the current user
+type PersonalDataController struct {
+	personalDataService *services.PersonalDataService
+}
+
+// NewPersonalDataController initializes a new PersonalDataController
+func NewPersonalDataController(personalDataService *services.PersonalDataService) *PersonalDataController {
+	return &PersonalDataController{personalDataService: personalDataService}
+}
+
+// ExportData downloads everything stored about the current user as a JSON file
+func (ctrl *PersonalDataController) ExportData(c *gin.Context) {
+	data, err := ctrl.personalDataService.Export(c.Request.Context(), c.GetUint("userID"))
+	if errors.Is(err, services.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	export := dto.PersonalDataExport{
+		ExportedAt:  time.Now(),
+		User:        toUserResponse(data.User),
+		Identities:  make([]dto.IdentityResponse, 0, len(data.Identities)),
+		APIKeys:     make([]dto.APIKeyResponse, 0, len(data.APIKeys)),
+		Sessions:    make([]dto.SessionResponse, 0, len(data.Sessions)),
+		QuizResults: data.QuizResults,
+		Answers:     data.Answers,
+		QuizLogs:    data.QuizLogs,
+	}
+	for _, identity := range data.Identities {
+		export.Identities = append(export.Identities, dto.IdentityResponse{
+			Issuer:    identity.Issuer,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		})
+	}
+	for i := range data.APIKeys {
+		export.APIKeys = append(export.APIKeys, toAPIKeyResponse(&data.APIKeys[i]))
+	}
+	for _, session := range data.Sessions {
+		export.Sessions = append(export.Sessions, dto.SessionResponse{
+			UUID:       session.UUID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.UUID == c.GetString("sessionUUID"),
+		})
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="personal-data-%s.json"`, data.User.UUID))
+	c.IndentedJSON(http.StatusOK, export)
+}
+
+// DeleteAccount erases the personal data of the current user and ends every session
+func (ctrl *PersonalDataController) DeleteAccount(c *gin.Context) {
+	var request dto.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := ctrl.personalDataService.Delete(c.Request.Context(), c.GetUint("userID"), request.Password, request.Confirm)
+	switch {
+	case errors.Is(err, services.ErrDeletionNotConfirmed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrAdminDeletion):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Your account and personal data have been deleted"})
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 	"net/http"
+	"quiz-api/dto"
 	"quiz-api/models"
 	"quiz-api/services"
 	"quiz-api/utils"
@@ -29,7 +30,7 @@ func (ctrl *QuizController) CreateQuiz(c *gin.Context) {
 		return
 	}
 
-	if err := ctrl.quizService.CreateQuiz(&quiz); err != nil {
+	if err := ctrl.quizService.CreateQuiz(&quiz, c.GetString("userUUID")); err != nil {
 		utils.SendError(c, 500, fmt.Sprintf("Failed to create quiz: %v", err))
 		return
 	}
@@ -101,7 +102,12 @@ func (ctrl *QuizController) UpdateQuiz(c *gin.Context) {
 		return
 	}
 
-	utils.SendSuccess(c, quiz)
+	updated, err := ctrl.quizService.GetQuizByUUID(uuid)
+	if err != nil {
+		utils.SendError(c, 500, err.Error())
+		return
+	}
+	utils.SendSuccess(c, updated)
 }
 
 // DeleteQuiz deletes a quiz by UUID
@@ -120,17 +126,61 @@ func (ctrl *QuizController) DeleteQuiz(c *gin.Context) {
 	utils.SendSuccess(c, nil)
 }
 
+// GetCollaborators lists the collaborators of a quiz
+func (ctrl *QuizController) GetCollaborators(c *gin.Context) {
+	uuid := c.Param("uuid")
+
+	collaborators, err := ctrl.quizService.GetCollaborators(uuid)
+	if err != nil {
+		utils.SendError(c, 500, fmt.Sprintf("Failed to retrieve collaborators: %v", err))
+		return
+	}
+
+	utils.SendSuccess(c, collaborators)
+}
+
+// SaveCollaborator shares a quiz with another user
+func (ctrl *QuizController) SaveCollaborator(c *gin.Context) {
+	uuid := c.Param("uuid")
+	var request dto.CollaboratorRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendError(c, 400, "Invalid input data")
+		return
+	}
+
+	collaborator, err := ctrl.quizService.SaveCollaborator(uuid, request.UserUUID, request.Permission)
+	if err != nil {
+		utils.SendError(c, 500, fmt.Sprintf("Failed to save collaborator: %v", err))
+		return
+	}
+
+	utils.SendSuccess(c, collaborator)
+}
+
+// RemoveCollaborator stops sharing a quiz with a user
+func (ctrl *QuizController) RemoveCollaborator(c *gin.Context) {
+	uuid := c.Param("uuid")
+	userUUID := c.Param("user-uuid")
+
+	if err := ctrl.quizService.RemoveCollaborator(uuid, userUUID); err != nil {
+		utils.SendError(c, 500, fmt.Sprintf("Failed to remove collaborator: %v", err))
+		return
+	}
+
+	utils.SendSuccess(c, nil)
+}
+
 // QuizExport exports a quiz using Kafka
 func (ctrl *QuizController) QuizExport(c *gin.Context) {
 	uuid := c.Param("uuid")
-	socketID := c.Query("socket_id")
+	userUUID := c.GetString("userUUID")
 
-	if uuid == "" || socketID == "" {
-		utils.SendError(c, 400, "UUID and socket_id are required")
+	if uuid == "" {
+		utils.SendError(c, 400, "UUID is required")
 		return
 	}
 
-	if err := ctrl.quizService.QuizExport(uuid, socketID); err != nil {
+	if err := ctrl.quizService.QuizExport(uuid, userUUID); err != nil {
 		utils.SendError(c, 500, fmt.Sprintf("Failed to export quiz with UUID %s: %v", uuid, err))
 		return
 	}
@@ -140,14 +190,14 @@ func (ctrl *QuizController) QuizExport(c *gin.Context) {
 
 func (ctrl *QuizController) RevokeQuiz(c *gin.Context) {
 	uuid := c.Param("uuid")
-	socketID := c.Query("socket_id")
+	userUUID := c.GetString("userUUID")
 
-	if uuid == "" || socketID == "" {
-		utils.SendError(c, 400, "UUID and socket_id are required")
+	if uuid == "" {
+		utils.SendError(c, 400, "UUID is required")
 		return
 	}
 
-	if err := ctrl.quizService.RevokeQuiz(uuid, socketID); err != nil {
+	if err := ctrl.quizService.RevokeQuiz(uuid, userUUID); err != nil {
 		utils.SendError(c, 500, fmt.Sprintf("Failed to export quiz with UUID %s: %v", uuid, err))
 		return
 	}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"quiz-api/dto"
+	"quiz-api/services"
+	"quiz-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TwoFactorController handles TOTP enrolment and the second login step
+type TwoFactorController struct {
+	twoFactorService *services.TwoFactorService
+	policyService    *services.PolicyService
+}
+
+// NewTwoFactorController initializes a new TwoFactorController
+func NewTwoFactorController(twoFactorService *services.TwoFactorService, policyService *services.PolicyService) *TwoFactorController {
+	return &TwoFactorController{twoFactorService: twoFactorService, policyService: policyService}
+}
+
+// respondTwoFactorChallenge answers a login that still needs a second factor and reports whether it did
+func respondTwoFactorChallenge(c *gin.Context, err error) bool {
+	var challenge *services.TwoFactorChallengeError
+	if !errors.As(err, &challenge) {
+		return false
+	}
+	c.JSON(http.StatusUnauthorized, dto.TwoFactorChallengeResponse{
+		Error:          err.Error(),
+		Code:           "TWO_FACTOR_REQUIRED",
+		ChallengeToken: challenge.Token,
+		ExpiresIn:      challenge.ExpiresIn,
+	})
+	return true
+}
+
+// Login completes a login with a TOTP or recovery code
+func (ctrl *TwoFactorController) Login(c *gin.Context) {
+	var request dto.TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, tokens, err := ctrl.twoFactorService.CompleteLogin(c.Request.Context(), request.ChallengeToken, request.Code, c.ClientIP())
+	var blocked *services.LoginBlockedError
+	if errors.As(err, &blocked) {
+		status := http.StatusTooManyRequests
+		if blocked.Code == services.LoginErrorLocked {
+			status = http.StatusLocked
+		}
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		c.JSON(status, gin.H{"error": err.Error(), "code": blocked.Code})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.LoginResponse{
+		ID:                     user.ID,
+		UUID:                   user.UUID,
+		Username:               user.Username,
+		Email:                  user.Email,
+		FullName:               user.FullName,
+		IsAdmin:                user.IsAdmin,
+		Role:                   user.Role,
+		PasswordResetRequired:  user.PasswordResetRequired,
+		TwoFactorSetupRequired: utils.TwoFactorSetupRequired(user),
+		Token:                  tokens.Token,
+		RefreshToken:           tokens.RefreshToken,
+		ExpiresIn:              tokens.ExpiresIn,
+	})
+}
+
+// Status reports whether 2FA is enabled for the current user
+func (ctrl *TwoFactorController) Status(c *gin.Context) {
+	user, err := ctrl.policyService.CurrentUser(c.GetUint("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	remaining, err := ctrl.twoFactorService.RemainingRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.TwoFactorStatusResponse{
+		Enabled:                user.TOTPEnabled,
+		Required:               utils.TwoFactorRequired(user),
+		RemainingRecoveryCodes: remaining,
+	})
+}
+
+// Enroll starts the enrolment and returns the secret with its provisioning URI
+func (ctrl *TwoFactorController) Enroll(c *gin.Context) {
+	enrollment, err := ctrl.twoFactorService.BeginEnrollment(c.Request.Context(), c.GetUint("userID"))
+	if err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// Verify confirms the enrolment with a first code and returns the recovery codes
+func (ctrl *TwoFactorController) Verify(c *gin.Context) {
+	var request dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := ctrl.twoFactorService.ConfirmEnrollment(c.Request.Context(), c.GetUint("userID"), request.Code)
+	if err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the current user
+func (ctrl *TwoFactorController) RegenerateRecoveryCodes(c *gin.Context) {
+	var request dto.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := ctrl.twoFactorService.RegenerateRecoveryCodes(c.Request.Context(), c.GetUint("userID"), request.Code)
+	if err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable turns 2FA off for the current user
+func (ctrl *TwoFactorController) Disable(c *gin.Context) {
+	var request dto.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ctrl.twoFactorService.Disable(c.Request.Context(), c.GetUint("userID"), request.Password, request.Code); err != nil {
+		sendTwoFactorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// ResetTwoFactor lets an admin turn 2FA off for a user who lost their authenticator
+func (ctrl *TwoFactorController) ResetTwoFactor(c *gin.Context) {
+	if err := ctrl.twoFactorService.Reset(c.Request.Context(), c.Param("uuid")); err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.SendError(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrTwoFactorNotEnabled):
+			utils.SendError(c, http.StatusConflict, err.Error())
+		default:
+			utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SendSuccess(c, gin.H{"message": "Two-factor authentication reset"})
+}
+
+func sendTwoFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrTwoFactorEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTwoFactorRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTwoFactorCode), errors.Is(err, services.ErrNoPendingEnrollment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	}
+}
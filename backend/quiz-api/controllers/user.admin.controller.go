@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"quiz-api/dto"
+	"quiz-api/models"
+	"quiz-api/services"
+	"quiz-api/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserAdminController handles user management endpoints for administrators
+type UserAdminController struct {
+	userAdminService *services.UserAdminService
+}
+
+// NewUserAdminController initializes a new UserAdminController
+func NewUserAdminController(userAdminService *services.UserAdminService) *UserAdminController {
+	return &UserAdminController{userAdminService: userAdminService}
+}
+
+// GetUsers lists and searches users
+func (ctrl *UserAdminController) GetUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	users, total, err := ctrl.userAdminService.SearchUsers(c.Query("q"), page, limit)
+	if err != nil {
+		utils.SendError(c, 500, fmt.Sprintf("Failed to retrieve users: %v", err))
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+
+	data := make([]dto.UserResponse, 0, len(users))
+	for i := range users {
+		data = append(data, toUserResponse(&users[i]))
+	}
+
+	response := map[string]interface{}{
+		"data": data,
+		"pagination": map[string]interface{}{
+			"currentPage": page,
+			"pageSize":    limit,
+			"totalItems":  total,
+			"totalPages":  totalPages,
+		},
+	}
+
+	utils.SendSuccess(c, response)
+}
+
+// GetUser retrieves a single user by UUID
+func (ctrl *UserAdminController) GetUser(c *gin.Context) {
+	user, err := ctrl.userAdminService.GetUser(c.Param("uuid"))
+	if err != nil {
+		sendUserAdminError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, toUserResponse(user))
+}
+
+// UpdateRole changes the role of a user
+func (ctrl *UserAdminController) UpdateRole(c *gin.Context) {
+	var request dto.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendError(c, 400, "Invalid input data")
+		return
+	}
+
+	user, err := ctrl.userAdminService.UpdateRole(c.GetString("userUUID"), c.Param("uuid"), request.Role)
+	if err != nil {
+		sendUserAdminError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, toUserResponse(user))
+}
+
+// GrantAdmin gives a user the admin role
+func (ctrl *UserAdminController) GrantAdmin(c *gin.Context) {
+	ctrl.setAdmin(c, true)
+}
+
+// RevokeAdmin removes the admin role from a user
+func (ctrl *UserAdminController) RevokeAdmin(c *gin.Context) {
+	ctrl.setAdmin(c, false)
+}
+
+func (ctrl *UserAdminController) setAdmin(c *gin.Context, isAdmin bool) {
+	user, err := ctrl.userAdminService.SetAdmin(c.GetString("userUUID"), c.Param("uuid"), isAdmin)
+	if err != nil {
+		sendUserAdminError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, toUserResponse(user))
+}
+
+// DisableUser disables an account and revokes its sessions
+func (ctrl *UserAdminController) DisableUser(c *gin.Context) {
+	ctrl.setDisabled(c, true)
+}
+
+// EnableUser re-enables a disabled account
+func (ctrl *UserAdminController) EnableUser(c *gin.Context) {
+	ctrl.setDisabled(c, false)
+}
+
+func (ctrl *UserAdminController) setDisabled(c *gin.Context, disabled bool) {
+	user, err := ctrl.userAdminService.SetDisabled(c.GetString("userUUID"), c.Param("uuid"), disabled)
+	if err != nil {
+		sendUserAdminError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, toUserResponse(user))
+}
+
+// ForcePasswordReset sets a temporary password that the user must change on next login
+func (ctrl *UserAdminController) ForcePasswordReset(c *gin.Context) {
+	temporaryPassword, err := ctrl.userAdminService.ForcePasswordReset(c.Param("uuid"))
+	if err != nil {
+		sendUserAdminError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, dto.ForcePasswordResetResponse{TemporaryPassword: temporaryPassword})
+}
+
+// UnlockUser lifts a login lockout on a user
+func (ctrl *UserAdminController) UnlockUser(c *gin.Context) {
+	user, err := ctrl.userAdminService.UnlockUser(c.GetString("userUUID"), c.Param("uuid"))
+	if err != nil {
+		sendUserAdminError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, toUserResponse(user))
+}
+
+// UnlockIP lifts a login lockout on a client IP
+func (ctrl *UserAdminController) UnlockIP(c *gin.Context) {
+	if err := ctrl.userAdminService.UnlockIP(c.GetString("userUUID"), c.Param("ip")); err != nil {
+		sendUserAdminError(c, err)
+		return
+	}
+
+	utils.SendSuccess(c, nil)
+}
+
+func sendUserAdminError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.SendError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrSelfManagement):
+		utils.SendError(c, http.StatusBadRequest, err.Error())
+	default:
+		utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func toUserResponse(user *models.User) dto.UserResponse {
+	return dto.UserResponse{
+		ID:                    user.ID,
+		UUID:                  user.UUID,
+		Username:              user.Username,
+		Email:                 user.Email,
+		EmailVerified:         user.EmailVerified,
+		FullName:              user.FullName,
+		IsAdmin:               user.IsAdmin,
+		Role:                  user.Role,
+		IsDisabled:            user.IsDisabled,
+		PasswordResetRequired: user.PasswordResetRequired,
+		TwoFactorEnabled:      user.TOTPEnabled,
+		CreatedAt:             user.CreatedAt,
+		UpdatedAt:             user.UpdatedAt,
+	}
+}
@@ -1,9 +1,13 @@
 package controllers
 
 import (
+	"errors"
+	"math"
 	"net/http"
 	"quiz-api/dto"
 	"quiz-api/services"
+	"quiz-api/utils"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,7 +28,11 @@ func (u *UserController) Register(c *gin.Context) {
 		return
 	}
 
-	user, err := u.UserService.Register(request.Username, request.Password, request.FullName)
+	user, err := u.UserService.Register(c.Request.Context(), request.Username, request.Password, request.FullName, request.Email)
+	if errors.Is(err, services.ErrEmailAlreadyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -33,6 +41,7 @@ func (u *UserController) Register(c *gin.Context) {
 	c.JSON(http.StatusCreated, dto.RegisterResponse{
 		ID:       user.ID,
 		Username: user.Username,
+		Email:    user.Email,
 	})
 }
 
@@ -44,22 +53,62 @@ func (u *UserController) Login(c *gin.Context) {
 		return
 	}
 
-	user, token, err := u.UserService.Login(request.Username, request.Password)
+	user, tokens, err := u.UserService.Login(request.Username, request.Password, c.Request.UserAgent(), c.ClientIP())
+	if respondTwoFactorChallenge(c, err) {
+		return
+	}
+	var blocked *services.LoginBlockedError
+	if errors.As(err, &blocked) {
+		status := http.StatusTooManyRequests
+		if blocked.Code == services.LoginErrorLocked {
+			status = http.StatusLocked
+		}
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+		c.JSON(status, gin.H{"error": err.Error(), "code": blocked.Code})
+		return
+	}
+	if errors.Is(err, services.ErrEmailNotVerified) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error(), "code": "EMAIL_NOT_VERIFIED"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, dto.LoginResponse{
-		ID:       user.ID,
-		UUID:     user.UUID,
-		Username: user.Username,
-		FullName: user.FullName,
-		IsAdmin:  user.IsAdmin,
-		Token:    token,
+		ID:                     user.ID,
+		UUID:                   user.UUID,
+		Username:               user.Username,
+		Email:                  user.Email,
+		FullName:               user.FullName,
+		IsAdmin:                user.IsAdmin,
+		Role:                   user.Role,
+		PasswordResetRequired:  user.PasswordResetRequired,
+		TwoFactorSetupRequired: utils.TwoFactorSetupRequired(user),
+		Token:                  tokens.Token,
+		RefreshToken:           tokens.RefreshToken,
+		ExpiresIn:              tokens.ExpiresIn,
 	})
 }
 
+// RefreshToken exchanges a refresh token for a new token pair
+func (u *UserController) RefreshToken(c *gin.Context) {
+	var request dto.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := u.UserService.RefreshToken(request.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
 // Change password
 func (u *UserController) ChangePassword(c *gin.Context) {
 	var request dto.ChangePasswordRequest
@@ -82,7 +131,8 @@ func (u *UserController) ChangePassword(c *gin.Context) {
 
 func (u *UserController) Logout(c *gin.Context) {
 	userID := c.GetUint("userID")
-	err := u.UserService.Logout(userID)
+	sessionUUID := c.GetString("sessionUUID")
+	err := u.UserService.Logout(userID, sessionUUID)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
@@ -90,3 +140,158 @@ func (u *UserController) Logout(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"message": "Logout successfully"})
 }
+
+// ListSessions lists the active sessions of the current user
+func (u *UserController) ListSessions(c *gin.Context) {
+	userID := c.GetUint("userID")
+	currentSession := c.GetString("sessionUUID")
+
+	sessions, err := u.UserService.ListSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := make([]dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, dto.SessionResponse{
+			UUID:       session.UUID,
+			UserAgent:  session.UserAgent,
+			IP:         session.IP,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			Current:    session.UUID == currentSession,
+		})
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession revokes one session of the current user
+func (u *UserController) RevokeSession(c *gin.Context) {
+	userID := c.GetUint("userID")
+	sessionUUID := c.Param("uuid")
+
+	err := u.UserService.RevokeSession(userID, sessionUUID)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked successfully"})
+}
+
+// RevokeAllSessions revokes every session of the current user, including the current one
+func (u *UserController) RevokeAllSessions(c *gin.Context) {
+	userID := c.GetUint("userID")
+
+	if err := u.UserService.RevokeAllSessions(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All sessions revoked successfully"})
+}
+
+// VerifyEmail redeems an email verification link
+func (u *UserController) VerifyEmail(c *gin.Context) {
+	var request dto.EmailTokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := u.UserService.VerifyEmail(request.Token)
+	if errors.Is(err, services.ErrInvalidEmailToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, services.ErrEmailAlreadyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully", "email": user.Email})
+}
+
+// ResendVerification sends a new verification link
+func (u *UserController) ResendVerification(c *gin.Context) {
+	var request dto.EmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := u.UserService.ResendVerification(c.Request.Context(), request.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the address needs verification, a link has been sent"})
+}
+
+// ChangeEmail sends a verification link to a new address of the current user
+func (u *UserController) ChangeEmail(c *gin.Context) {
+	var request dto.EmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := u.UserService.ChangeEmail(c.Request.Context(), c.GetUint("userID"), request.Email)
+	if errors.Is(err, services.ErrEmailAlreadyExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "A verification link has been sent to the new address"})
+}
+
+// ForgotPassword emails a password reset link
+func (u *UserController) ForgotPassword(c *gin.Context) {
+	var request dto.EmailRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := u.UserService.RequestPasswordReset(c.Request.Context(), request.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If the address belongs to an account, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password from a reset link
+func (u *UserController) ResetPassword(c *gin.Context) {
+	var request dto.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := u.UserService.ResetPassword(c.Request.Context(), request.Token, request.NewPassword)
+	if errors.Is(err, services.ErrInvalidEmailToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
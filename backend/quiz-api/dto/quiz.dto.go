@@ -29,3 +29,36 @@ type UserQuizDTO struct {
 	Score     int       `json:"score"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// CollaboratorRequest represents the structure for sharing a quiz with a user
+type CollaboratorRequest struct {
+	UserUUID   string `json:"user_uuid" binding:"required"`
+	Permission string `json:"permission" binding:"required,oneof=editor viewer"`
+}
+
+// JoinCodeResponse carries a join code guests can use to play a quiz
+type JoinCodeResponse struct {
+	Code      string    `json:"code"`
+	QuizUUID  string    `json:"quiz_uuid"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GuestJoinRequest represents the structure for joining a quiz as a guest
+type GuestJoinRequest struct {
+	Code     string `json:"code" binding:"required"`
+	Nickname string `json:"nickname" binding:"required,max=32"`
+}
+
+// GuestJoinResponse carries the guest token, valid only for the joined quiz
+type GuestJoinResponse struct {
+	Token     string `json:"token"`
+	UUID      string `json:"uuid"`
+	Nickname  string `json:"nickname"`
+	QuizUUID  string `json:"quiz_uuid"`
+	ExpiresIn int64  `json:"expires_in"`
+}
+
+// PurgeGuestsResponse reports how many guests had their results removed
+type PurgeGuestsResponse struct {
+	Purged int `json:"purged"`
+}
@@ -1,16 +1,23 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // RegisterRequest represents the structure for user registration
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
 	FullName string `json:"fullname" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
 }
 
 // RegisterResponse represents the response after successful registration
 type RegisterResponse struct {
 	ID       uint   `json:"id"`
 	Username string `json:"username"`
+	Email    string `json:"email"`
 }
 
 // LoginRequest represents the structure for user login
@@ -23,10 +30,18 @@ type LoginRequest struct {
 type LoginResponse struct {
 	ID       uint   `json:"id"`
 	Username string `json:"username"`
+	Email    string `json:"email"`
 	FullName string `json:"fullname"`
 	UUID     string `json:"uuid"`
 	IsAdmin  bool   `json:"is_admin"`
-	Token    string `json:"token"`
+	Role     string `json:"role"`
+	// PasswordResetRequired means every endpoint except change-password is refused until the password is changed
+	PasswordResetRequired bool `json:"password_reset_required"`
+	// TwoFactorSetupRequired means every endpoint except 2FA enrolment is refused until it is enabled
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required"`
+	Token                  string `json:"token"`
+	RefreshToken           string `json:"refresh_token"`
+	ExpiresIn              int64  `json:"expires_in"`
 }
 
 // ChangePasswordRequest represents the structure for changing password
@@ -34,3 +49,183 @@ type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required"`
 	NewPassword string `json:"new_password" binding:"required"`
 }
+
+// RefreshTokenRequest represents the structure for exchanging a refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// TokenResponse represents a freshly issued access and refresh token pair
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// SessionResponse represents an active session of the current user
+type SessionResponse struct {
+	UUID       string    `json:"uuid"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	Current    bool      `json:"current"`
+}
+
+// UpdateRoleRequest represents the structure for changing a user's role
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin author reviewer learner"`
+}
+
+// UserResponse represents a user as seen by administrators
+type UserResponse struct {
+	ID                    uint      `json:"id"`
+	UUID                  string    `json:"uuid"`
+	Username              string    `json:"username"`
+	Email                 string    `json:"email"`
+	EmailVerified         bool      `json:"email_verified"`
+	FullName              string    `json:"fullname"`
+	IsAdmin               bool      `json:"is_admin"`
+	Role                  string    `json:"role"`
+	IsDisabled            bool      `json:"is_disabled"`
+	PasswordResetRequired bool      `json:"password_reset_required"`
+	TwoFactorEnabled      bool      `json:"two_factor_enabled"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// ForcePasswordResetResponse carries the temporary password to hand over to the user
+type ForcePasswordResetResponse struct {
+	TemporaryPassword string `json:"temporary_password"`
+}
+
+// OIDCAuthorizeResponse carries the identity provider URL to redirect the browser to.
+// The client keeps the binding (e.g. in sessionStorage) and sends it back with the callback.
+type OIDCAuthorizeResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	Binding          string `json:"binding"`
+}
+
+// OIDCCallbackRequest carries the values the identity provider redirected back with
+// and the binding returned when the sign-on was started
+type OIDCCallbackRequest struct {
+	Code    string `json:"code" binding:"required"`
+	State   string `json:"state" binding:"required"`
+	Binding string `json:"binding" binding:"required"`
+}
+
+// CreateAPIKeyRequest represents the structure for issuing an API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	UserUUID  string     `json:"user_uuid"` // User the key acts as; defaults to the issuing administrator
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyResponse describes an API key without its secret
+type APIKeyResponse struct {
+	UUID       string     `json:"uuid"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	UserID     uint       `json:"user_id"`
+	CreatedBy  uint       `json:"created_by"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResponse carries the new key, which is shown only once
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// EmailRequest carries an email address, to resend a verification link, request a password reset or change the address
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// EmailTokenRequest carries the token of an email link
+type EmailTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// ResetPasswordRequest represents the structure for choosing a new password from a reset link
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// DeleteAccountRequest confirms an account deletion. Password is required for accounts
+// that sign in with one; accounts that only use single sign-on send Confirm "DELETE" instead.
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Confirm  string `json:"confirm"`
+}
+
+// IdentityResponse describes an external identity linked to a user
+type IdentityResponse struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PersonalDataExport holds everything stored about a user
+type PersonalDataExport struct {
+	ExportedAt  time.Time                  `json:"exported_at"`
+	User        UserResponse               `json:"user"`
+	Identities  []IdentityResponse         `json:"identities"`
+	APIKeys     []APIKeyResponse           `json:"api_keys"`
+	Sessions    []SessionResponse          `json:"sessions"`
+	QuizResults []map[string]interface{}   `json:"quiz_results"` // user_quizs rows
+	Answers     []map[string]interface{}   `json:"answers"`      // user_answers rows
+	QuizLogs    map[string]json.RawMessage `json:"quiz_logs"`    // static/logs files by name
+}
+
+// TwoFactorChallengeResponse is returned by login when a second factor is still needed
+type TwoFactorChallengeResponse struct {
+	Error          string `json:"error"`
+	Code           string `json:"code"`
+	ChallengeToken string `json:"challenge_token"`
+	ExpiresIn      int64  `json:"expires_in"`
+}
+
+// TwoFactorLoginRequest completes a login with a TOTP code or a recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// TwoFactorEnrollmentResponse carries the pending secret; ProvisioningURI is meant to be shown as a QR code
+type TwoFactorEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// TwoFactorCodeRequest carries a code from the authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorDisableRequest requires the password and a TOTP or recovery code
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// RecoveryCodesResponse lists new recovery codes; they cannot be retrieved again
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatusResponse describes the 2FA state of the current user
+type TwoFactorStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RemainingRecoveryCodes int64 `json:"remaining_recovery_codes"`
+}
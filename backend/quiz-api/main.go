@@ -1,9 +1,12 @@
 package main
 
 import (
+	"log"
 	"net/http"
+	"os"
 	"quiz-api/containers"
 	"quiz-api/routes"
+	"strings"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -14,10 +17,18 @@ func main() {
 
 	router := gin.Default()
 
+	// ClientIP feeds the rate limits and the login lockout, so forwarding headers are only
+	// honoured from the proxies listed in TRUSTED_PROXIES (comma separated IPs or CIDRs)
+	trustedProxies := strings.Fields(strings.ReplaceAll(os.Getenv("TRUSTED_PROXIES"), ",", " "))
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
+
 	router.Use(cors.New(cors.Config{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "Cache-Control", "Pragma", "Expires"},
+		AllowOrigins:  []string{"*"},
+		AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Authorization", "X-API-Key", "Cache-Control", "Pragma", "Expires"},
+		ExposeHeaders: []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 	}))
 
 	staticHandler := http.FileServer(http.Dir("./static"))
@@ -27,6 +38,7 @@ func main() {
 
 	err := routes.RegisterRoutes(router, container)
 	go containers.RunKafkaConsumer(container)
+	go containers.RunKeyRotation(container)
 
 	if err != nil {
 		panic(err)
@@ -2,10 +2,9 @@ package middlewares
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
-	"os"
-	"quiz-api/config"
+	"quiz-api/services"
 	"strings"
 	"time"
 
@@ -16,8 +15,23 @@ import (
 type AdminMiddleware gin.HandlerFunc
 
 // AdminMiddleware checks if the user is an admin based on the JWT token
-func NewAdminMiddleware(redisClient *config.RedisClient) AdminMiddleware {
+func NewAdminMiddleware(sessionService *services.SessionService, policyService *services.PolicyService, apiKeyService *services.APIKeyService, signingKeyService *services.SigningKeyService) AdminMiddleware {
 	return func(c *gin.Context) {
+		if rawKey, ok := apiKeyFromRequest(c); ok {
+			apiKey := authenticateAPIKey(c, apiKeyService, rawKey)
+			if apiKey == nil {
+				return
+			}
+			isAdmin, err := policyService.IsAdmin(apiKey.UserID)
+			if err != nil || !isAdmin || !apiKey.HasScope(string(services.ActionUserManage)) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
@@ -32,9 +46,7 @@ func NewAdminMiddleware(redisClient *config.RedisClient) AdminMiddleware {
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		token, err := jwt.Parse(tokenString, signingKeyService.Keyfunc, jwt.WithValidMethods(signingKeyService.ValidMethods()))
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
@@ -42,42 +54,56 @@ func NewAdminMiddleware(redisClient *config.RedisClient) AdminMiddleware {
 		}
 
 		jwtClaims, ok := token.Claims.(jwt.MapClaims)
+		rawUserID, hasUserID := jwtClaims["user_id"].(float64)
+		sessionUUID, hasSession := jwtClaims["session_uuid"].(string)
 
-		if !ok {
+		// Guest tokens carry neither and never reach admin routes
+		if !ok || !hasUserID || !hasSession {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid token claims"})
 			c.Abort()
 			return
 		}
 
-		userID := uint(jwtClaims["user_id"].(float64))
-		sessionUUID := jwtClaims["session_uuid"].(string)
+		userID := uint(rawUserID)
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		redisKey := "user:" + fmt.Sprint(userID)
-		keySession := ""
-		err = redisClient.Get(ctx, redisKey, &keySession)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check session in Redis"})
+		if _, err := sessionService.Validate(ctx, userID, sessionUUID); err != nil {
+			if errors.Is(err, services.ErrSessionNotFound) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found or revoked"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check session in Redis"})
+			}
+			c.Abort()
+			return
+		}
+
+		// No admin route changes the password, so a pending reset blocks them all
+		if resetRequired, _ := jwtClaims["pwd_reset"].(bool); resetRequired {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
 			c.Abort()
 			return
 		}
 
-		if keySession != sessionUUID {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found or mismatch"})
+		if setupRequired, _ := jwtClaims["mfa_setup"].(bool); setupRequired {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication setup required", "code": "TWO_FACTOR_SETUP_REQUIRED"})
 			c.Abort()
 			return
 		}
 
-		// Check the isAdmin field
-		isAdmin, ok := jwtClaims["is_admin"].(bool)
-		if !ok || !isAdmin {
+		// Check the current role rather than the is_admin claim, so a revoked admin loses access immediately
+		isAdmin, err := policyService.IsAdmin(userID)
+		if err != nil || !isAdmin {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
 			c.Abort()
 			return
 		}
 
+		c.Set("userID", userID)
+		c.Set("userUUID", jwtClaims["user_uuid"])
+		c.Set("sessionUUID", sessionUUID)
+
 		// User is an admin; proceed with the request
 		c.Next()
 	}
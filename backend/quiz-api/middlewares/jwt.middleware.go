@@ -2,10 +2,9 @@ package middlewares
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"net/http"
-	"os"
-	"quiz-api/config"
+	"quiz-api/services"
 	"strings"
 	"time"
 
@@ -15,8 +14,35 @@ import (
 
 type JWTMiddleware gin.HandlerFunc
 
-func NewJWTMiddleware(redisClient *config.RedisClient) JWTMiddleware {
+// passwordResetAllowedPaths are the routes reachable while a password reset is pending
+var passwordResetAllowedPaths = map[string]bool{
+	"/change-password": true,
+	"/logout":          true,
+}
+
+// twoFactorSetupAllowedPaths are the routes reachable by an admin who still has to enable 2FA
+var twoFactorSetupAllowedPaths = map[string]bool{
+	"/2fa":        true,
+	"/2fa/enroll": true,
+	"/2fa/verify": true,
+	"/logout":     true,
+}
+
+// guestAllowedPaths are the only routes a guest token can reach, and only for the quiz it was issued for
+var guestAllowedPaths = map[string]bool{
+	"/quiz-status/:quiz-uuid": true,
+	"/top-scores/:quiz-uuid":  true,
+}
+
+func NewJWTMiddleware(sessionService *services.SessionService, apiKeyService *services.APIKeyService, signingKeyService *services.SigningKeyService, guestService *services.GuestService) JWTMiddleware {
 	return func(c *gin.Context) {
+		if rawKey, ok := apiKeyFromRequest(c); ok {
+			if authenticateAPIKey(c, apiKeyService, rawKey) != nil {
+				c.Next()
+			}
+			return
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
@@ -31,9 +57,7 @@ func NewJWTMiddleware(redisClient *config.RedisClient) JWTMiddleware {
 			return
 		}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
+		token, err := jwt.Parse(tokenString, signingKeyService.Keyfunc, jwt.WithValidMethods(signingKeyService.ValidMethods()))
 		if err != nil || !token.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
@@ -41,6 +65,10 @@ func NewJWTMiddleware(redisClient *config.RedisClient) JWTMiddleware {
 		}
 
 		claims, ok := token.Claims.(jwt.MapClaims)
+		if isGuest, _ := claims["guest"].(bool); ok && isGuest {
+			authenticateGuest(c, guestService, claims)
+			return
+		}
 		if !ok || claims["user_id"] == nil || claims["session_uuid"] == nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
 			c.Abort()
@@ -55,24 +83,65 @@ func NewJWTMiddleware(redisClient *config.RedisClient) JWTMiddleware {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 		defer cancel()
 
-		redisKey := "user:" + fmt.Sprint(userID)
-		keySession := ""
-		err = redisClient.Get(ctx, redisKey, &keySession)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check session in Redis"})
+		if _, err := sessionService.Validate(ctx, userID, sessionUUID); err != nil {
+			if errors.Is(err, services.ErrSessionNotFound) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found or revoked"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check session in Redis"})
+			}
 			c.Abort()
 			return
 		}
 
-		if keySession != sessionUUID {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found or mismatch"})
+		// A forced password reset leaves the session usable only to change the password
+		if resetRequired, _ := claims["pwd_reset"].(bool); resetRequired && !passwordResetAllowedPaths[c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
+			c.Abort()
+			return
+		}
+		// Likewise an admin without 2FA can only enrol while REQUIRE_ADMIN_2FA is set;
+		// refreshing the token after enrolment lifts the restriction
+		if setupRequired, _ := claims["mfa_setup"].(bool); setupRequired && !twoFactorSetupAllowedPaths[c.FullPath()] {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Two-factor authentication setup required", "code": "TWO_FACTOR_SETUP_REQUIRED"})
 			c.Abort()
 			return
 		}
 
 		c.Set("userID", userID)
 		c.Set("userUUID", userUUID)
+		c.Set("sessionUUID", sessionUUID)
 		c.Set("fullName", fullName)
 		c.Next()
 	}
 }
+
+// authenticateGuest lets a guest token through to the guest routes of its own quiz
+func authenticateGuest(c *gin.Context, guestService *services.GuestService, claims jwt.MapClaims) {
+	guestUUID, _ := claims["user_uuid"].(string)
+	quizUUID, _ := claims["quiz_uuid"].(string)
+	nickname, _ := claims["fullname"].(string)
+
+	if !guestAllowedPaths[c.FullPath()] || c.Param("quiz-uuid") != quizUUID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Guest access is limited to the joined quiz"})
+		c.Abort()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := guestService.ValidateGuest(ctx, guestUUID, quizUUID); err != nil {
+		if errors.Is(err, services.ErrGuestNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check guest session in Redis"})
+		}
+		c.Abort()
+		return
+	}
+
+	c.Set("userUUID", guestUUID)
+	c.Set("fullName", nickname)
+	c.Set("guest", true)
+	c.Next()
+}
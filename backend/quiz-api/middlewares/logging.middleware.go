@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"quiz-api/models"
 	"runtime"
 	"time"
 
@@ -53,6 +54,15 @@ func logRequest(logger *logrus.Logger, c *gin.Context, duration int64) {
 		"response_time": formatDuration(duration),
 	}
 
+	// Attribute automated traffic to the API key that made it
+	if apiKey, ok := c.Get("apiKey"); ok {
+		if key, ok := apiKey.(*models.APIKey); ok {
+			fields["api_key_uuid"] = key.UUID
+			fields["api_key_name"] = key.Name
+			fields["user_id"] = key.UserID
+		}
+	}
+
 	// Log errors if present
 	if len(c.Errors) > 0 {
 		fields["errors"] = c.Errors.String()
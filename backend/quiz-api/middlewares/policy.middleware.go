@@ -0,0 +1,122 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"quiz-api/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidResourceBody = errors.New("invalid input data")
+
+// ResourceResolver finds the UUID of the quiz a request targets
+type ResourceResolver func(c *gin.Context, policyService *services.PolicyService) (string, error)
+
+// PolicyMiddleware builds a handler that checks the current user may perform action on the resolved quiz.
+// It must run after the JWT middleware.
+type PolicyMiddleware func(action services.Action, resource ResourceResolver) gin.HandlerFunc
+
+func NewPolicyMiddleware(policyService *services.PolicyService) PolicyMiddleware {
+	return func(action services.Action, resource ResourceResolver) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			// An API key is limited to its scopes on top of the permissions of its user
+			if !apiKeyAllows(c, action) {
+				abortWithPolicyError(c, services.ErrForbidden)
+				return
+			}
+
+			quizUUID := ""
+			if resource != nil {
+				var err error
+				quizUUID, err = resource(c, policyService)
+				if err != nil {
+					abortWithPolicyError(c, err)
+					return
+				}
+			}
+
+			if err := policyService.Authorize(c.GetUint("userID"), action, quizUUID); err != nil {
+				abortWithPolicyError(c, err)
+				return
+			}
+
+			c.Next()
+		}
+	}
+}
+
+func abortWithPolicyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrResourceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errInvalidResourceBody):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+	}
+	c.Abort()
+}
+
+// QuizParam resolves the quiz from a path parameter holding its UUID
+func QuizParam(name string) ResourceResolver {
+	return func(c *gin.Context, _ *services.PolicyService) (string, error) {
+		return c.Param(name), nil
+	}
+}
+
+// QuestionParam resolves the quiz from a path parameter holding a question UUID
+func QuestionParam(name string) ResourceResolver {
+	return func(c *gin.Context, policyService *services.PolicyService) (string, error) {
+		return policyService.QuizUUIDForQuestion(c.Param(name))
+	}
+}
+
+// AnswerParam resolves the quiz from a path parameter holding an answer UUID
+func AnswerParam(name string) ResourceResolver {
+	return func(c *gin.Context, policyService *services.PolicyService) (string, error) {
+		return policyService.QuizUUIDForAnswer(c.Param(name))
+	}
+}
+
+// QuizBody resolves the quiz from the quiz_uuid field of the JSON body
+func QuizBody() ResourceResolver {
+	return func(c *gin.Context, _ *services.PolicyService) (string, error) {
+		return bodyField(c, "quiz_uuid")
+	}
+}
+
+// QuestionBody resolves the quiz from the question_uuid field of the JSON body
+func QuestionBody() ResourceResolver {
+	return func(c *gin.Context, policyService *services.PolicyService) (string, error) {
+		questionUUID, err := bodyField(c, "question_uuid")
+		if err != nil {
+			return "", err
+		}
+		return policyService.QuizUUIDForQuestion(questionUUID)
+	}
+}
+
+// bodyField reads a string field from the JSON body and restores the body for the controller
+func bodyField(c *gin.Context, field string) (string, error) {
+	bodyBytes, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return "", errInvalidResourceBody
+	}
+	c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return "", errInvalidResourceBody
+	}
+	value, ok := body[field].(string)
+	if !ok || value == "" {
+		return "", errInvalidResourceBody
+	}
+	return value, nil
+}
@@ -0,0 +1,63 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"quiz-api/models"
+	"quiz-api/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RateLimitMiddleware builds a handler enforcing the rate limit of a route group.
+// Clients are identified by user UUID when authenticated, so it should run after the JWT middleware on protected routes.
+type RateLimitMiddleware func(group string) gin.HandlerFunc
+
+func NewRateLimitMiddleware(rateLimitService *services.RateLimitService, logger *logrus.Logger) RateLimitMiddleware {
+	return func(group string) gin.HandlerFunc {
+		policy := services.NewRateLimitPolicy(group)
+
+		return func(c *gin.Context) {
+			clientKey := "ip:" + c.ClientIP()
+			if apiKey, ok := c.Get("apiKey"); ok {
+				clientKey = "api_key:" + apiKey.(*models.APIKey).UUID
+			} else if userUUID := c.GetString("userUUID"); userUUID != "" {
+				clientKey = "user:" + userUUID
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			result, err := rateLimitService.Allow(ctx, policy, clientKey)
+			if err != nil {
+				// Fail open: an unavailable Redis must not take the whole API down
+				logger.WithError(err).WithField("group", group).Warn("Rate limit check failed")
+				c.Next()
+				return
+			}
+
+			c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+			c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+
+			if !result.Allowed {
+				c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded"})
+				c.Abort()
+				return
+			}
+
+			c.Next()
+		}
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKey lets a non-interactive client act as a user, limited to its scopes.
+// Only the SHA-256 hash of the key is stored; Prefix identifies it in listings.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UUID       string     `gorm:"type:char(36);uniqueIndex" json:"uuid"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"not null" json:"prefix"`
+	KeyHash    string     `gorm:"type:char(64);uniqueIndex;not null" json:"-"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	User       User       `gorm:"foreignKey:UserID" json:"-"`
+	CreatedBy  uint       `gorm:"not null" json:"created_by"`
+	Scopes     []string   `gorm:"serializer:json;type:text" json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) (err error) {
+	if k.UUID == "" {
+		k.UUID = uuid.New().String()
+	}
+	return
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the key is neither revoked nor expired
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// GuestPlayer is a player who joined a quiz with a join code instead of an account.
+// It records which results in ScyllaDB belong to guests so they can be purged on their own.
+type GuestPlayer struct {
+	UUID      string    `gorm:"type:char(36);primaryKey" json:"uuid"`
+	QuizUUID  string    `gorm:"type:char(36);index;not null" json:"quiz_uuid"`
+	Nickname  string    `gorm:"not null" json:"nickname"`
+	JoinCode  string    `gorm:"not null" json:"join_code"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -6,12 +6,14 @@ import (
 
 // Quiz represents a quiz with a title and associated questions
 type Quiz struct {
-	UUID        string     `gorm:"type:uuid;primary_key;" json:"uuid"`
-	Title       string     `json:"title"`
-	IsPublished bool       `gorm:"default:false" json:"is_published"` // Indicates if the quiz is published
-	Questions   []Question `gorm:"foreignKey:QuizUUID;constraint:OnDelete:CASCADE;" json:"questions,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	UUID          string             `gorm:"type:uuid;primary_key;" json:"uuid"`
+	Title         string             `json:"title"`
+	IsPublished   bool               `gorm:"default:false" json:"is_published"` // Indicates if the quiz is published
+	OwnerUUID     string             `gorm:"type:char(36);index" json:"owner_uuid"`
+	Questions     []Question         `gorm:"foreignKey:QuizUUID;constraint:OnDelete:CASCADE;" json:"questions,omitempty"`
+	Collaborators []QuizCollaborator `gorm:"foreignKey:QuizUUID;constraint:OnDelete:CASCADE;" json:"collaborators,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
 }
 
 // Question represents a question in a quiz
@@ -37,3 +39,17 @@ type Answer struct {
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 }
+
+const (
+	CollaboratorEditor = "editor"
+	CollaboratorViewer = "viewer"
+)
+
+// QuizCollaborator grants a user access to a quiz they do not own
+type QuizCollaborator struct {
+	QuizUUID   string    `gorm:"type:uuid;primaryKey" json:"quiz_uuid"`
+	UserUUID   string    `gorm:"type:char(36);primaryKey" json:"user_uuid"`
+	Permission string    `gorm:"not null" json:"permission"` // editor or viewer
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
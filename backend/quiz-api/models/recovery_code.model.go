@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the authenticator is lost.
+// Only the SHA-256 digest of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"type:char(64);not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
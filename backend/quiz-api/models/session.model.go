@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Session represents a single logged-in device of a user, stored in Redis
+type Session struct {
+	UUID       string    `json:"uuid"`
+	UserID     uint      `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// SigningKey is an asymmetric key used to sign access tokens.
+// Only the newest unretired key signs; retired keys stay published until ExpiresAt
+// so tokens they signed can still be verified.
+type SigningKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	Kid        string     `gorm:"uniqueIndex;not null" json:"kid"`
+	Algorithm  string     `gorm:"not null" json:"algorithm"`
+	PrivateKey string     `gorm:"type:text;not null" json:"-"` // PKCS #8 PEM
+	CreatedAt  time.Time  `json:"created_at"`
+	RetiredAt  *time.Time `json:"retired_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
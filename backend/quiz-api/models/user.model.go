@@ -7,20 +7,49 @@ import (
 	"gorm.io/gorm"
 )
 
+const (
+	RoleAdmin    = "admin"
+	RoleAuthor   = "author"
+	RoleReviewer = "reviewer"
+	RoleLearner  = "learner"
+)
+
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	UUID      string    `gorm:"type:char(36);uniqueIndex" json:"uuid"`
-	Username  string    `gorm:"unique;not null" json:"username"`
-	FullName  string    `gorm:"unique;not null" json:"fullname"`
-	Password  string    `gorm:"not null" json:"password"`
-	IsAdmin   bool      `gorm:"default:false" json:"is_admin"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                    uint      `gorm:"primaryKey" json:"id"`
+	UUID                  string    `gorm:"type:char(36);uniqueIndex" json:"uuid"`
+	Username              string    `gorm:"unique;not null" json:"username"`
+	Email                 string    `gorm:"index:idx_users_email,unique,where:email <> ''" json:"email"`
+	EmailVerified         bool      `gorm:"default:false" json:"email_verified"`
+	FullName              string    `gorm:"unique;not null" json:"fullname"`
+	Password              string    `gorm:"not null" json:"password"`
+	IsAdmin               bool      `gorm:"default:false" json:"is_admin"`
+	Role                  string    `gorm:"default:learner;not null" json:"role"`
+	IsDisabled            bool      `gorm:"default:false" json:"is_disabled"`
+	PasswordResetRequired bool      `gorm:"default:false" json:"password_reset_required"`
+	TOTPSecret            string    `json:"-"`
+	TOTPEnabled           bool      `gorm:"default:false" json:"totp_enabled"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	if u.UUID == "" {
 		u.UUID = uuid.New().String()
 	}
+	if u.Role == "" {
+		u.Role = RoleLearner
+		if u.IsAdmin {
+			u.Role = RoleAdmin
+		}
+	}
 	return
 }
+
+// IsValidRole reports whether role is one of the known user roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleAuthor, RoleReviewer, RoleLearner:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external OpenID Connect provider
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Issuer    string    `gorm:"not null;uniqueIndex:idx_identity_issuer_subject" json:"issuer"`
+	Subject   string    `gorm:"not null;uniqueIndex:idx_identity_issuer_subject" json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
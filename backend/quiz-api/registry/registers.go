@@ -9,14 +9,14 @@ import (
 )
 
 // RegisterTopics sets up topics and their associated handlers
-func RegisterTopics(logger *logrus.Logger, quizExportSerice *services.QuizExportService) services.KafkaConfig {
+func RegisterTopics(logger *logrus.Logger, quizExportSerice *services.QuizExportService, notificationService *services.NotificationService) services.KafkaConfig {
 	return services.KafkaConfig{
 		Broker:    os.Getenv("KAFKA_BROKER"),
 		GroupID:   os.Getenv("KAFKA_GROUP_ID"),
 		Username:  os.Getenv("KAFKA_USERNAME"),
 		Password:  os.Getenv("KAFKA_PASSWORD"),
 		Topics:    []string{"quiz_export", "revoke_quiz", "user_quiz_export"},
-		Consumers: consumers.RegisterKafkaConsumers(logger, quizExportSerice),
+		Consumers: consumers.RegisterKafkaConsumers(logger, quizExportSerice, notificationService),
 		Logger:    logger,
 	}
 }
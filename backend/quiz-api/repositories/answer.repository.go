@@ -23,6 +23,13 @@ func (r *AnswerRepository) CreateAnswer(answer *models.Answer) error {
 	return r.db.Create(answer).Error
 }
 
+// GetAnswerByUUID retrieves an answer by its UUID
+func (r *AnswerRepository) GetAnswerByUUID(uuid string) (*models.Answer, error) {
+	var answer models.Answer
+	err := r.db.First(&answer, "uuid = ?", uuid).Error
+	return &answer, err
+}
+
 // GetAnswersByQuestionUUID retrieves answers by the question UUID
 func (r *AnswerRepository) GetAnswersByQuestionUUID(questionUUID string) ([]models.Answer, error) {
 	var answers []models.Answer
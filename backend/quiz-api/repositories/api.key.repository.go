@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"errors"
+	"quiz-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository struct {
+	DB *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *APIKeyRepository {
+	return &APIKeyRepository{DB: db}
+}
+
+func (r *APIKeyRepository) Create(apiKey *models.APIKey) error {
+	return r.DB.Create(apiKey).Error
+}
+
+// FindByHash returns the key with its user, or nil when no key matches
+func (r *APIKeyRepository) FindByHash(keyHash string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	err := r.DB.Preload("User").Where("key_hash = ?", keyHash).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &apiKey, err
+}
+
+// FindByUUID returns the key, or nil when it does not exist
+func (r *APIKeyRepository) FindByUUID(keyUUID string) (*models.APIKey, error) {
+	var apiKey models.APIKey
+	err := r.DB.Where("uuid = ?", keyUUID).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &apiKey, err
+}
+
+// GetAll retrieves every key, newest first
+func (r *APIKeyRepository) GetAll() ([]models.APIKey, error) {
+	var apiKeys []models.APIKey
+	err := r.DB.Order("created_at DESC").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+func (r *APIKeyRepository) Revoke(keyID uint, revokedAt time.Time) error {
+	return r.DB.Model(&models.APIKey{}).Where("id = ?", keyID).Update("revoked_at", revokedAt).Error
+}
+
+func (r *APIKeyRepository) UpdateLastUsed(keyID uint, usedAt time.Time, ip string) error {
+	return r.DB.Model(&models.APIKey{}).Where("id = ?", keyID).Updates(map[string]interface{}{
+		"last_used_at": usedAt,
+		"last_used_ip": ip,
+	}).Error
+}
+
+// GetByUser retrieves the keys acting as a user
+func (r *APIKeyRepository) GetByUser(userID uint) ([]models.APIKey, error) {
+	var apiKeys []models.APIKey
+	err := r.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&apiKeys).Error
+	return apiKeys, err
+}
@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"quiz-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type GuestRepository struct {
+	DB *gorm.DB
+}
+
+func NewGuestRepository(db *gorm.DB) *GuestRepository {
+	return &GuestRepository{DB: db}
+}
+
+func (r *GuestRepository) Create(guest *models.GuestPlayer) error {
+	return r.DB.Create(guest).Error
+}
+
+// FindForPurge retrieves the guests of a quiz, or of every quiz when quizUUID is empty, created before the given time
+func (r *GuestRepository) FindForPurge(quizUUID string, before time.Time) ([]models.GuestPlayer, error) {
+	var guests []models.GuestPlayer
+	db := r.DB.Where("created_at < ?", before)
+	if quizUUID != "" {
+		db = db.Where("quiz_uuid = ?", quizUUID)
+	}
+	err := db.Find(&guests).Error
+	return guests, err
+}
+
+func (r *GuestRepository) Delete(guestUUID string) error {
+	return r.DB.Where("uuid = ?", guestUUID).Delete(&models.GuestPlayer{}).Error
+}
@@ -24,6 +24,13 @@ func (r *QuestionRepository) CreateQuestion(question *models.Question) error {
 	return r.db.Create(question).Error
 }
 
+// GetQuestionByUUID retrieves a question by its UUID
+func (r *QuestionRepository) GetQuestionByUUID(uuid string) (*models.Question, error) {
+	var question models.Question
+	err := r.db.First(&question, "uuid = ?", uuid).Error
+	return &question, err
+}
+
 // GetQuestionsByQuizUUID retrieves questions by the quiz UUID with pagination
 // GetQuestionsByQuiz retrieves paginated questions for a quiz
 func (r *QuestionRepository) GetQuestionsByQuiz(quizUUID string, offset, limit int) ([]models.Question, int64, error) {
@@ -44,12 +44,44 @@ func (r *QuizRepository) GetQuizzesWithPagination(offset, limit int) ([]models.Q
 	return quizzes, total, err
 }
 
-// UpdateQuiz updates an existing quiz
+// UpdateQuiz updates the title of an existing quiz; associations are left untouched
 func (r *QuizRepository) UpdateQuiz(uuid string, updatedQuiz *models.Quiz) error {
-	return r.db.Model(&models.Quiz{}).Where("uuid = ?", uuid).Save(updatedQuiz).Error
+	return r.db.Model(&models.Quiz{}).Where("uuid = ?", uuid).
+		Updates(map[string]interface{}{"title": updatedQuiz.Title}).Error
 }
 
 // DeleteQuiz deletes a quiz by its UUID
 func (r *QuizRepository) DeleteQuiz(uuid string) error {
 	return r.db.Delete(&models.Quiz{}, "uuid = ?", uuid).Error
 }
+
+// GetQuizOwner retrieves a quiz without its questions, used for authorization checks
+func (r *QuizRepository) GetQuizOwner(uuid string) (*models.Quiz, error) {
+	var quiz models.Quiz
+	err := r.db.Select("uuid", "owner_uuid").First(&quiz, "uuid = ?", uuid).Error
+	return &quiz, err
+}
+
+// GetCollaborator retrieves the collaborator entry of a user on a quiz
+func (r *QuizRepository) GetCollaborator(quizUUID, userUUID string) (*models.QuizCollaborator, error) {
+	var collaborator models.QuizCollaborator
+	err := r.db.First(&collaborator, "quiz_uuid = ? AND user_uuid = ?", quizUUID, userUUID).Error
+	return &collaborator, err
+}
+
+// GetCollaborators retrieves all collaborators of a quiz
+func (r *QuizRepository) GetCollaborators(quizUUID string) ([]models.QuizCollaborator, error) {
+	var collaborators []models.QuizCollaborator
+	err := r.db.Where("quiz_uuid = ?", quizUUID).Find(&collaborators).Error
+	return collaborators, err
+}
+
+// SaveCollaborator adds a collaborator to a quiz or updates their permission
+func (r *QuizRepository) SaveCollaborator(collaborator *models.QuizCollaborator) error {
+	return r.db.Save(collaborator).Error
+}
+
+// DeleteCollaborator removes a collaborator from a quiz
+func (r *QuizRepository) DeleteCollaborator(quizUUID, userUUID string) error {
+	return r.db.Delete(&models.QuizCollaborator{}, "quiz_uuid = ? AND user_uuid = ?", quizUUID, userUUID).Error
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"quiz-api/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SigningKeyRepository struct {
+	DB *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) *SigningKeyRepository {
+	return &SigningKeyRepository{DB: db}
+}
+
+// GetValid retrieves the keys that have not expired, newest first
+func (r *SigningKeyRepository) GetValid(now time.Time) ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	err := r.DB.Where("expires_at IS NULL OR expires_at > ?", now).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Rotate stores a new signing key and retires the previous ones in one transaction
+func (r *SigningKeyRepository) Rotate(key *models.SigningKey, retiredAt, expiresAt time.Time) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.SigningKey{}).Where("retired_at IS NULL").Updates(map[string]interface{}{
+			"retired_at": retiredAt,
+			"expires_at": expiresAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(key).Error
+	})
+}
@@ -3,6 +3,7 @@ package repositories
 import (
 	"errors"
 	"quiz-api/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -37,3 +38,179 @@ func (r *UserRepository) FindById(userId uint) (*models.User, error) {
 func (r *UserRepository) UpdatePassword(userID uint, newPassword string) error {
 	return r.DB.Model(&models.User{}).Where("id = ?", userID).Update("password", newPassword).Error
 }
+
+func (r *UserRepository) FindByUUID(userUUID string) (*models.User, error) {
+	var user models.User
+	err := r.DB.Where("uuid = ?", userUUID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &user, err
+}
+
+func (r *UserRepository) UpdateRole(userID uint, role string) error {
+	return r.DB.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"role":     role,
+		"is_admin": role == models.RoleAdmin,
+	}).Error
+}
+
+// SearchUsers retrieves paginated users whose username or full name contains query
+func (r *UserRepository) SearchUsers(query string, offset, limit int) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	db := r.DB.Model(&models.User{})
+	if query != "" {
+		pattern := "%" + query + "%"
+		db = db.Where("username ILIKE ? OR full_name ILIKE ?", pattern, pattern)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Order("created_at DESC").Offset(offset).Limit(limit).Find(&users).Error
+	return users, total, err
+}
+
+func (r *UserRepository) UpdateFields(userID uint, fields map[string]interface{}) error {
+	return r.DB.Model(&models.User{}).Where("id = ?", userID).Updates(fields).Error
+}
+
+// FindByIdentity returns the user linked to an external identity, or nil when none is linked
+func (r *UserRepository) FindByIdentity(issuer, subject string) (*models.User, error) {
+	var identity models.UserIdentity
+	err := r.DB.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.FindById(identity.UserID)
+}
+
+func (r *UserRepository) CreateIdentity(identity *models.UserIdentity) error {
+	return r.DB.Create(identity).Error
+}
+
+// CreateWithIdentity provisions a user and links the external identity in one transaction
+func (r *UserRepository) CreateWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+// ExistsByFullName reports whether the full name is already taken
+func (r *UserRepository) ExistsByFullName(fullName string) (bool, error) {
+	var count int64
+	err := r.DB.Model(&models.User{}).Where("full_name = ?", fullName).Count(&count).Error
+	return count > 0, err
+}
+
+// FindByEmail returns the user with the email address, or nil when none has it
+func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := r.DB.Where("email = ?", email).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return &user, err
+}
+
+// FindIdentities retrieves the external identities linked to a user
+func (r *UserRepository) FindIdentities(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	err := r.DB.Where("user_id = ?", userID).Find(&identities).Error
+	return identities, err
+}
+
+// Anonymize replaces the personal fields of a user and removes the rows that point at them
+// (linked identities, API keys, quiz collaborations and recovery codes) in one transaction.
+// The row itself is kept so quizzes it owns stay consistent.
+func (r *UserRepository) Anonymize(user *models.User, fields map[string]interface{}) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.APIKey{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_uuid = ?", user.UUID).Delete(&models.QuizCollaborator{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(fields).Error
+	})
+}
+
+// EnableTwoFactor stores a confirmed TOTP secret and replaces the recovery codes in one transaction
+func (r *UserRepository) EnableTwoFactor(userID uint, secret string, codeHashes []string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":  secret,
+			"totp_enabled": true,
+		}).Error
+	})
+}
+
+// DisableTwoFactor clears the TOTP secret and removes the recovery codes of a user
+func (r *UserRepository) DisableTwoFactor(userID uint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"totp_secret":  "",
+			"totp_enabled": false,
+		}).Error
+	})
+}
+
+// ReplaceRecoveryCodes discards the recovery codes of a user and stores a new set
+func (r *UserRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+// UseRecoveryCode marks an unused recovery code as used and reports whether one matched.
+// The conditional update makes concurrent attempts with the same code succeed only once.
+func (r *UserRepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	now := time.Now()
+	result := r.DB.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", &now)
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (r *UserRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]models.RecoveryCode, len(codeHashes))
+	for i, hash := range codeHashes {
+		codes[i] = models.RecoveryCode{UserID: userID, CodeHash: hash}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	"quiz-api/controllers"
 	"quiz-api/middlewares"
+	"quiz-api/services"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/dig"
@@ -10,15 +11,15 @@ import (
 
 // RegisterAnswerRoutes sets up routes for managing answers
 func AnswerRoutes(router *gin.Engine, container *dig.Container) error {
-	err := container.Invoke(func(answerController *controllers.AnswerController, adminMiddleware middlewares.AdminMiddleware, loggingMiddleware middlewares.LoggingMiddleware) {
+	err := container.Invoke(func(answerController *controllers.AnswerController, jwtMiddleware middlewares.JWTMiddleware, policyMiddleware middlewares.PolicyMiddleware, rateLimitMiddleware middlewares.RateLimitMiddleware, loggingMiddleware middlewares.LoggingMiddleware) {
 
 		answerGroup := router.Group("/answers")
 		{
-			answerGroup.Use(gin.HandlerFunc(adminMiddleware))
-			answerGroup.POST("/", answerController.CreateAnswer)
-			answerGroup.GET("/question/:uuid", answerController.GetAnswersByQuestion)
-			answerGroup.PUT("/:uuid", answerController.UpdateAnswer)
-			answerGroup.DELETE("/:uuid", answerController.DeleteAnswer)
+			answerGroup.Use(gin.HandlerFunc(jwtMiddleware), rateLimitMiddleware(services.RateLimitGroupManagement))
+			answerGroup.POST("/", policyMiddleware(services.ActionQuizUpdate, middlewares.QuestionBody()), answerController.CreateAnswer)
+			answerGroup.GET("/question/:uuid", policyMiddleware(services.ActionQuizRead, middlewares.QuestionParam("uuid")), answerController.GetAnswersByQuestion)
+			answerGroup.PUT("/:uuid", policyMiddleware(services.ActionQuizUpdate, middlewares.AnswerParam("uuid")), answerController.UpdateAnswer)
+			answerGroup.DELETE("/:uuid", policyMiddleware(services.ActionQuizUpdate, middlewares.AnswerParam("uuid")), answerController.DeleteAnswer)
 		}
 	})
 
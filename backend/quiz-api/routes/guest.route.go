@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"quiz-api/controllers"
+	"quiz-api/middlewares"
+	"quiz-api/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/dig"
+)
+
+// GuestRoutes sets up join codes for administrators and the guest join endpoint
+func GuestRoutes(router *gin.Engine, container *dig.Container) error {
+	err := container.Invoke(func(guestController *controllers.GuestController, adminMiddleware middlewares.AdminMiddleware, rateLimitMiddleware middlewares.RateLimitMiddleware) {
+		managementLimit := rateLimitMiddleware(services.RateLimitGroupManagement)
+		router.POST("/quizzes/:uuid/join-codes", gin.HandlerFunc(adminMiddleware), managementLimit, guestController.CreateJoinCode)
+		router.DELETE("/join-codes/:code", gin.HandlerFunc(adminMiddleware), managementLimit, guestController.RevokeJoinCode)
+		router.DELETE("/guests", gin.HandlerFunc(adminMiddleware), managementLimit, guestController.PurgeGuests)
+
+		router.POST("/guest/join", rateLimitMiddleware(services.RateLimitGroupAuth), guestController.Join)
+	})
+
+	return err
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"quiz-api/controllers"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/dig"
+)
+
+// JWKSRoutes publishes the token verification keys for other services
+func JWKSRoutes(router *gin.Engine, container *dig.Container) error {
+	err := container.Invoke(func(jwksController *controllers.JWKSController) {
+		router.GET("/.well-known/jwks.json", jwksController.GetJWKS)
+	})
+
+	return err
+}
@@ -0,0 +1,28 @@
+package routes
+
+import (
+	"quiz-api/controllers"
+	"quiz-api/middlewares"
+	"quiz-api/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/dig"
+)
+
+func OIDCRoutes(router *gin.Engine, container *dig.Container) error {
+	err := container.Invoke(func(oidcController *controllers.OIDCController, jwtMiddleware middlewares.JWTMiddleware, rateLimitMiddleware middlewares.RateLimitMiddleware) {
+		oidcGroup := router.Group("/oidc")
+		{
+			oidcGroup.Use(rateLimitMiddleware(services.RateLimitGroupAuth))
+			oidcGroup.GET("/authorize", oidcController.Authorize)
+			oidcGroup.POST("/callback", oidcController.Callback)
+
+			// Session only: API keys are refused on the whole group
+			linkGroup := oidcGroup.Group("/link", gin.HandlerFunc(jwtMiddleware))
+			linkGroup.POST("", oidcController.Link)
+			linkGroup.POST("/callback", oidcController.LinkCallback)
+		}
+	})
+
+	return err
+}
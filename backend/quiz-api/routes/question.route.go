@@ -3,6 +3,7 @@ package routes
 import (
 	"quiz-api/controllers"
 	"quiz-api/middlewares"
+	"quiz-api/services"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/dig"
@@ -10,15 +11,15 @@ import (
 
 // RegisterQuizRoutes sets up routes for managing quizzes
 func QuestionRoutes(router *gin.Engine, container *dig.Container) error {
-	err := container.Invoke(func(questionController *controllers.QuestionController, adminMiddleware middlewares.AdminMiddleware, loggingMiddleware middlewares.LoggingMiddleware) {
+	err := container.Invoke(func(questionController *controllers.QuestionController, jwtMiddleware middlewares.JWTMiddleware, policyMiddleware middlewares.PolicyMiddleware, rateLimitMiddleware middlewares.RateLimitMiddleware, loggingMiddleware middlewares.LoggingMiddleware) {
 
 		questionGroup := router.Group("/questions")
 		{
-			questionGroup.Use(gin.HandlerFunc(adminMiddleware))
-			questionGroup.POST("/", questionController.CreateQuestion)
-			questionGroup.GET("/quiz/:uuid", questionController.GetQuestionsByQuiz)
-			questionGroup.PUT("/:uuid", questionController.UpdateQuestion)
-			questionGroup.DELETE("/:uuid", questionController.DeleteQuestion)
+			questionGroup.Use(gin.HandlerFunc(jwtMiddleware), rateLimitMiddleware(services.RateLimitGroupManagement))
+			questionGroup.POST("/", policyMiddleware(services.ActionQuizUpdate, middlewares.QuizBody()), questionController.CreateQuestion)
+			questionGroup.GET("/quiz/:uuid", policyMiddleware(services.ActionQuizRead, middlewares.QuizParam("uuid")), questionController.GetQuestionsByQuiz)
+			questionGroup.PUT("/:uuid", policyMiddleware(services.ActionQuizUpdate, middlewares.QuestionParam("uuid")), questionController.UpdateQuestion)
+			questionGroup.DELETE("/:uuid", policyMiddleware(services.ActionQuizUpdate, middlewares.QuestionParam("uuid")), questionController.DeleteQuestion)
 		}
 	})
 
@@ -3,6 +3,7 @@ package routes
 import (
 	"quiz-api/controllers"
 	"quiz-api/middlewares"
+	"quiz-api/services"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/dig"
@@ -10,20 +11,25 @@ import (
 
 // RegisterQuizRoutes sets up routes for managing quizzes
 func QuizRoutes(router *gin.Engine, container *dig.Container) error {
-	err := container.Invoke(func(quizController *controllers.QuizController, jwtMiddleware middlewares.JWTMiddleware, adminMiddleware middlewares.AdminMiddleware, loggingMiddleware middlewares.LoggingMiddleware) {
+	err := container.Invoke(func(quizController *controllers.QuizController, jwtMiddleware middlewares.JWTMiddleware, policyMiddleware middlewares.PolicyMiddleware, rateLimitMiddleware middlewares.RateLimitMiddleware, loggingMiddleware middlewares.LoggingMiddleware) {
 
-		router.GET("quizzes/", gin.HandlerFunc(jwtMiddleware), quizController.GetQuizzes)
-		router.GET("quiz-status/:quiz-uuid", gin.HandlerFunc(jwtMiddleware), quizController.GetQuizStatus)
-		router.GET("top-scores/:quiz-uuid", gin.HandlerFunc(jwtMiddleware), quizController.GetTopScores)
+		gameplayLimit := rateLimitMiddleware(services.RateLimitGroupGameplay)
+		readScope := middlewares.RequireAPIKeyScope(services.ActionQuizRead)
+		router.GET("quizzes/", gin.HandlerFunc(jwtMiddleware), readScope, gameplayLimit, quizController.GetQuizzes)
+		router.GET("quiz-status/:quiz-uuid", gin.HandlerFunc(jwtMiddleware), readScope, gameplayLimit, quizController.GetQuizStatus)
+		router.GET("top-scores/:quiz-uuid", gin.HandlerFunc(jwtMiddleware), readScope, gameplayLimit, quizController.GetTopScores)
 		quizGroup := router.Group("/quizzes")
 		{
-			quizGroup.Use(gin.HandlerFunc(adminMiddleware))
-			quizGroup.POST("/", quizController.CreateQuiz)
-			quizGroup.GET("/:uuid", quizController.GetQuiz)
-			quizGroup.PUT("/:uuid", quizController.UpdateQuiz)
-			quizGroup.DELETE("/:uuid", quizController.DeleteQuiz)
-			quizGroup.GET("/quiz-export/:uuid", quizController.QuizExport)
-			quizGroup.GET("/revoke-quiz/:uuid", quizController.RevokeQuiz)
+			quizGroup.Use(gin.HandlerFunc(jwtMiddleware), rateLimitMiddleware(services.RateLimitGroupManagement))
+			quizGroup.POST("/", policyMiddleware(services.ActionQuizCreate, nil), quizController.CreateQuiz)
+			quizGroup.GET("/:uuid", policyMiddleware(services.ActionQuizRead, middlewares.QuizParam("uuid")), quizController.GetQuiz)
+			quizGroup.PUT("/:uuid", policyMiddleware(services.ActionQuizUpdate, middlewares.QuizParam("uuid")), quizController.UpdateQuiz)
+			quizGroup.DELETE("/:uuid", policyMiddleware(services.ActionQuizDelete, middlewares.QuizParam("uuid")), quizController.DeleteQuiz)
+			quizGroup.GET("/quiz-export/:uuid", policyMiddleware(services.ActionQuizPublish, middlewares.QuizParam("uuid")), quizController.QuizExport)
+			quizGroup.GET("/revoke-quiz/:uuid", policyMiddleware(services.ActionQuizPublish, middlewares.QuizParam("uuid")), quizController.RevokeQuiz)
+			quizGroup.GET("/:uuid/collaborators", policyMiddleware(services.ActionQuizRead, middlewares.QuizParam("uuid")), quizController.GetCollaborators)
+			quizGroup.PUT("/:uuid/collaborators", policyMiddleware(services.ActionQuizShare, middlewares.QuizParam("uuid")), quizController.SaveCollaborator)
+			quizGroup.DELETE("/:uuid/collaborators/:user-uuid", policyMiddleware(services.ActionQuizShare, middlewares.QuizParam("uuid")), quizController.RemoveCollaborator)
 		}
 	})
 
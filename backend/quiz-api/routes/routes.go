@@ -14,12 +14,27 @@ func RegisterRoutes(router *gin.Engine, container *dig.Container) error {
 		return err
 	}
 
+	if err := JWKSRoutes(router, container); err != nil {
+		return err
+	}
 	if err := UserRoutes(router, container); err != nil {
 		return err
 	}
+	if err := OIDCRoutes(router, container); err != nil {
+		return err
+	}
+	if err := TwoFactorRoutes(router, container); err != nil {
+		return err
+	}
+	if err := UserAdminRoutes(router, container); err != nil {
+		return err
+	}
 	if err := QuizRoutes(router, container); err != nil {
 		return err
 	}
+	if err := GuestRoutes(router, container); err != nil {
+		return err
+	}
 	if err := QuestionRoutes(router, container); err != nil {
 		return err
 	}
@@ -0,0 +1,29 @@
+package routes
+
+import (
+	"quiz-api/controllers"
+	"quiz-api/middlewares"
+	"quiz-api/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/dig"
+)
+
+func TwoFactorRoutes(router *gin.Engine, container *dig.Container) error {
+	err := container.Invoke(func(twoFactorController *controllers.TwoFactorController, jwtMiddleware middlewares.JWTMiddleware, rateLimitMiddleware middlewares.RateLimitMiddleware) {
+		authLimit := rateLimitMiddleware(services.RateLimitGroupAuth)
+		router.POST("/login/2fa", authLimit, twoFactorController.Login)
+
+		twoFactorGroup := router.Group("/2fa")
+		{
+			twoFactorGroup.Use(gin.HandlerFunc(jwtMiddleware), authLimit)
+			twoFactorGroup.GET("", twoFactorController.Status)
+			twoFactorGroup.POST("/enroll", twoFactorController.Enroll)
+			twoFactorGroup.POST("/verify", twoFactorController.Verify)
+			twoFactorGroup.POST("/recovery-codes", twoFactorController.RegenerateRecoveryCodes)
+			twoFactorGroup.POST("/disable", twoFactorController.Disable)
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"quiz-api/controllers"
+	"quiz-api/middlewares"
+	"quiz-api/services"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/dig"
+)
+
+// UserAdminRoutes sets up routes for administrators to manage users
+func UserAdminRoutes(router *gin.Engine, container *dig.Container) error {
+	err := container.Invoke(func(userAdminController *controllers.UserAdminController, apiKeyController *controllers.APIKeyController, twoFactorController *controllers.TwoFactorController, adminMiddleware middlewares.AdminMiddleware, rateLimitMiddleware middlewares.RateLimitMiddleware) {
+		userGroup := router.Group("/users")
+		{
+			userGroup.Use(gin.HandlerFunc(adminMiddleware), rateLimitMiddleware(services.RateLimitGroupManagement))
+			userGroup.GET("/", userAdminController.GetUsers)
+			userGroup.GET("/:uuid", userAdminController.GetUser)
+			userGroup.PUT("/:uuid/role", userAdminController.UpdateRole)
+			userGroup.PUT("/:uuid/admin", userAdminController.GrantAdmin)
+			userGroup.DELETE("/:uuid/admin", userAdminController.RevokeAdmin)
+			userGroup.PUT("/:uuid/disable", userAdminController.DisableUser)
+			userGroup.PUT("/:uuid/enable", userAdminController.EnableUser)
+			userGroup.POST("/:uuid/force-password-reset", userAdminController.ForcePasswordReset)
+			userGroup.PUT("/:uuid/unlock", rateLimitMiddleware(services.RateLimitGroupUnlock), userAdminController.UnlockUser)
+			userGroup.DELETE("/:uuid/2fa", twoFactorController.ResetTwoFactor)
+		}
+
+		apiKeyGroup := router.Group("/api-keys")
+		{
+			apiKeyGroup.Use(gin.HandlerFunc(adminMiddleware), rateLimitMiddleware(services.RateLimitGroupManagement))
+			apiKeyGroup.GET("/", apiKeyController.GetAPIKeys)
+			apiKeyGroup.POST("/", apiKeyController.CreateAPIKey)
+			apiKeyGroup.DELETE("/:uuid", apiKeyController.RevokeAPIKey)
+		}
+
+		router.DELETE("/login-lockouts/ip/:ip", gin.HandlerFunc(adminMiddleware), rateLimitMiddleware(services.RateLimitGroupUnlock), userAdminController.UnlockIP)
+	})
+
+	return err
+}
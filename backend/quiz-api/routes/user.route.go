@@ -3,17 +3,40 @@ package routes
 import (
 	"quiz-api/controllers"
 	"quiz-api/middlewares"
+	"quiz-api/services"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/dig"
 )
 
 func UserRoutes(router *gin.Engine, container *dig.Container) error {
-	err := container.Invoke(func(userController *controllers.UserController, quizController *controllers.QuizController, jwtMiddleware middlewares.JWTMiddleware, loggingMiddleware middlewares.LoggingMiddleware) {
-		router.POST("/register", userController.Register)
-		router.POST("/login", userController.Login)
+	err := container.Invoke(func(userController *controllers.UserController, personalDataController *controllers.PersonalDataController, quizController *controllers.QuizController, jwtMiddleware middlewares.JWTMiddleware, rateLimitMiddleware middlewares.RateLimitMiddleware, loggingMiddleware middlewares.LoggingMiddleware) {
+		authLimit := rateLimitMiddleware(services.RateLimitGroupAuth)
+		router.POST("/register", authLimit, userController.Register)
+		router.POST("/login", authLimit, userController.Login)
+		router.POST("/token/refresh", authLimit, userController.RefreshToken)
+		router.POST("/email/verify", authLimit, userController.VerifyEmail)
+		router.POST("/email/verification", authLimit, userController.ResendVerification)
+		router.PUT("/email", gin.HandlerFunc(jwtMiddleware), authLimit, userController.ChangeEmail)
+		router.POST("/password/forgot", authLimit, userController.ForgotPassword)
+		router.POST("/password/reset", authLimit, userController.ResetPassword)
 		router.PUT("/change-password", gin.HandlerFunc(jwtMiddleware), userController.ChangePassword)
 		router.GET("/logout", gin.HandlerFunc(jwtMiddleware), userController.Logout)
+
+		meGroup := router.Group("/me")
+		{
+			meGroup.Use(gin.HandlerFunc(jwtMiddleware), authLimit)
+			meGroup.GET("/data", personalDataController.ExportData)
+			meGroup.DELETE("", personalDataController.DeleteAccount)
+		}
+
+		sessionGroup := router.Group("/sessions")
+		{
+			sessionGroup.Use(gin.HandlerFunc(jwtMiddleware))
+			sessionGroup.GET("/", userController.ListSessions)
+			sessionGroup.DELETE("/", userController.RevokeAllSessions)
+			sessionGroup.DELETE("/:uuid", userController.RevokeSession)
+		}
 	})
 
 	return err
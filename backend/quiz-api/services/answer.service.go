@@ -27,6 +27,12 @@ func (s *AnswerService) GetAnswersByQuestion(questionUUID string) ([]models.Answ
 
 // UpdateAnswer updates an existing answer
 func (s *AnswerService) UpdateAnswer(uuid string, updatedAnswer *models.Answer) error {
+	existing, err := s.answerRepo.GetAnswerByUUID(uuid)
+	if err != nil {
+		return err
+	}
+	// Answers cannot be moved between questions; access was checked against the current question
+	updatedAnswer.QuestionUUID = existing.QuestionUUID
 	return s.answerRepo.UpdateAnswer(uuid, updatedAnswer)
 }
 
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quiz-api/config"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"quiz-api/utils"
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix marks a bearer credential as an API key rather than a JWT
+const APIKeyPrefix = "qk_"
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// APIKeyService issues and authenticates API keys for automated clients
+type APIKeyService struct {
+	apiKeyRepo  *repositories.APIKeyRepository
+	userRepo    *repositories.UserRepository
+	redisClient *config.RedisClient
+}
+
+// NewAPIKeyService initializes a new APIKeyService
+func NewAPIKeyService(apiKeyRepo *repositories.APIKeyRepository, userRepo *repositories.UserRepository, redisClient *config.RedisClient) *APIKeyService {
+	return &APIKeyService{apiKeyRepo: apiKeyRepo, userRepo: userRepo, redisClient: redisClient}
+}
+
+func apiKeySeenKey(keyID uint) string {
+	return "api_key_seen:" + fmt.Sprint(keyID)
+}
+
+// Create issues a key acting as the user with userUUID, or as the creator when it is empty.
+// The plain key is only returned here; afterwards only its hash is known.
+func (s *APIKeyService) Create(creatorID uint, userUUID, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	for _, scope := range scopes {
+		if !isAPIKeyScope(scope) {
+			return nil, "", fmt.Errorf("invalid scope %s", scope)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", errors.New("expiry must be in the future")
+	}
+
+	userID := creatorID
+	if userUUID != "" {
+		user, err := s.userRepo.FindByUUID(userUUID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to retrieve user: %w", err)
+		}
+		if user == nil {
+			return nil, "", ErrUserNotFound
+		}
+		userID = user.ID
+	}
+
+	secret, err := utils.GenerateSecret(32)
+	if err != nil {
+		return nil, "", err
+	}
+	rawKey := APIKeyPrefix + secret
+
+	apiKey := &models.APIKey{
+		Name:      name,
+		Prefix:    rawKey[:len(APIKeyPrefix)+8],
+		KeyHash:   utils.HashToken(rawKey),
+		UserID:    userID,
+		CreatedBy: creatorID,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.apiKeyRepo.Create(apiKey); err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+	return apiKey, rawKey, nil
+}
+
+// List retrieves every API key
+func (s *APIKeyService) List() ([]models.APIKey, error) {
+	apiKeys, err := s.apiKeyRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API keys: %w", err)
+	}
+	return apiKeys, nil
+}
+
+// Revoke disables a key immediately
+func (s *APIKeyService) Revoke(keyUUID string) (*models.APIKey, error) {
+	apiKey, err := s.apiKeyRepo.FindByUUID(keyUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve API key: %w", err)
+	}
+	if apiKey == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	if apiKey.RevokedAt != nil {
+		return apiKey, nil
+	}
+
+	now := time.Now()
+	if err := s.apiKeyRepo.Revoke(apiKey.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	apiKey.RevokedAt = &now
+	return apiKey, nil
+}
+
+// Authenticate resolves a plain key to an active key whose user is still enabled,
+// and records its last use at most once per minute.
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey, ip string) (*models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.apiKeyRepo.FindByHash(utils.HashToken(rawKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	now := time.Now()
+	if apiKey == nil || !apiKey.IsActive(now) || apiKey.User.IsDisabled {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if first, err := s.redisClient.SetNX(ctx, apiKeySeenKey(apiKey.ID), true, lastSeenResolution); err == nil && first {
+		s.apiKeyRepo.UpdateLastUsed(apiKey.ID, now, ip)
+	}
+	return apiKey, nil
+}
+
+func isAPIKeyScope(scope string) bool {
+	for _, action := range APIKeyScopes {
+		if string(action) == scope {
+			return true
+		}
+	}
+	return false
+}
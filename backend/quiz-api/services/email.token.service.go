@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"quiz-api/config"
+	"quiz-api/models"
+	"quiz-api/utils"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	EmailTokenVerification  = "email_verification"
+	EmailTokenPasswordReset = "password_reset"
+)
+
+var ErrInvalidEmailToken = errors.New("invalid, expired or already used link")
+
+// emailTokenClaims are carried by the links sent in emails
+type emailTokenClaims struct {
+	jwt.RegisteredClaims
+	Purpose string `json:"purpose"`
+	Email   string `json:"email"`
+}
+
+// EmailTokenService issues HMAC-signed, expiring, single-use tokens for email links.
+// Only the newest token of a purpose is valid for a user, and each one can be redeemed once.
+type EmailTokenService struct {
+	redisClient *config.RedisClient
+	secret      []byte
+	ttls        map[string]time.Duration
+}
+
+// NewEmailTokenService initializes a new EmailTokenService signing with EMAIL_TOKEN_SECRET
+func NewEmailTokenService(redisClient *config.RedisClient, logger *logrus.Logger) (*EmailTokenService, error) {
+	secret := []byte(os.Getenv("EMAIL_TOKEN_SECRET"))
+	if len(secret) == 0 {
+		// Links then only work on this instance until it restarts
+		logger.Warn("EMAIL_TOKEN_SECRET is not set, using a random secret")
+		generated, err := utils.GenerateSecret(32)
+		if err != nil {
+			return nil, err
+		}
+		secret = []byte(generated)
+	}
+
+	return &EmailTokenService{
+		redisClient: redisClient,
+		secret:      secret,
+		ttls: map[string]time.Duration{
+			EmailTokenVerification:  utils.DurationFromEnv("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+			EmailTokenPasswordReset: utils.DurationFromEnv("PASSWORD_RESET_TTL", time.Hour),
+		},
+	}, nil
+}
+
+func emailTokenKey(purpose, userUUID string) string {
+	return "email_token:" + purpose + ":" + userUUID
+}
+
+func emailTokenUsedKey(jti string) string {
+	return "email_token_used:" + jti
+}
+
+// Issue returns a token for the user and email, replacing any earlier token of the same purpose
+func (s *EmailTokenService) Issue(ctx context.Context, purpose string, user *models.User, email string) (string, error) {
+	ttl := s.ttls[purpose]
+	jti := uuid.New().String()
+
+	claims := emailTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Subject:   user.UUID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+		},
+		Purpose: purpose,
+		Email:   email,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+
+	if err := s.redisClient.Set(ctx, emailTokenKey(purpose, user.UUID), jti, ttl); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+	return token, nil
+}
+
+// Consume verifies a token of the given purpose and marks it as used
+func (s *EmailTokenService) Consume(ctx context.Context, purpose, token string) (*emailTokenClaims, error) {
+	claims := &emailTokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.Purpose != purpose {
+		return nil, ErrInvalidEmailToken
+	}
+
+	var latest string
+	if err := s.redisClient.Get(ctx, emailTokenKey(purpose, claims.Subject), &latest); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidEmailToken
+		}
+		return nil, fmt.Errorf("failed to load token: %w", err)
+	}
+	if latest != claims.ID {
+		return nil, ErrInvalidEmailToken
+	}
+
+	first, err := s.redisClient.SetNX(ctx, emailTokenUsedKey(claims.ID), true, time.Until(claims.ExpiresAt.Time))
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem token: %w", err)
+	}
+	if !first {
+		return nil, ErrInvalidEmailToken
+	}
+	s.redisClient.Delete(ctx, emailTokenKey(purpose, claims.Subject))
+	return claims, nil
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"quiz-api/models"
+	"testing"
+)
+
+func TestEmailTokenConsume(t *testing.T) {
+	user := &models.User{UUID: "user-uuid"}
+
+	tests := []struct {
+		name string
+		// token returns the token to consume, issued through s
+		token     func(t *testing.T, s *EmailTokenService) string
+		purpose   string
+		wantErr   error
+		wantEmail string
+	}{
+		{
+			name: "newest token",
+			token: func(t *testing.T, s *EmailTokenService) string {
+				return mustIssue(t, s, EmailTokenVerification, "a@example.com")
+			},
+			purpose:   EmailTokenVerification,
+			wantEmail: "a@example.com",
+		},
+		{
+			name: "token replaced by a newer one",
+			token: func(t *testing.T, s *EmailTokenService) string {
+				older := mustIssue(t, s, EmailTokenVerification, "a@example.com")
+				mustIssue(t, s, EmailTokenVerification, "b@example.com")
+				return older
+			},
+			purpose: EmailTokenVerification,
+			wantErr: ErrInvalidEmailToken,
+		},
+		{
+			name: "newer token of another purpose",
+			token: func(t *testing.T, s *EmailTokenService) string {
+				token := mustIssue(t, s, EmailTokenPasswordReset, "a@example.com")
+				mustIssue(t, s, EmailTokenVerification, "a@example.com")
+				return token
+			},
+			purpose:   EmailTokenPasswordReset,
+			wantEmail: "a@example.com",
+		},
+		{
+			name: "token of another purpose",
+			token: func(t *testing.T, s *EmailTokenService) string {
+				return mustIssue(t, s, EmailTokenVerification, "a@example.com")
+			},
+			purpose: EmailTokenPasswordReset,
+			wantErr: ErrInvalidEmailToken,
+		},
+		{
+			name: "token signed with another secret",
+			token: func(t *testing.T, s *EmailTokenService) string {
+				other := &EmailTokenService{redisClient: s.redisClient, secret: []byte("other"), ttls: s.ttls}
+				return mustIssue(t, other, EmailTokenVerification, "a@example.com")
+			},
+			purpose: EmailTokenVerification,
+			wantErr: ErrInvalidEmailToken,
+		},
+		{
+			name:    "malformed token",
+			token:   func(t *testing.T, s *EmailTokenService) string { return "not-a-token" },
+			purpose: EmailTokenVerification,
+			wantErr: ErrInvalidEmailToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, err := NewEmailTokenService(newTestRedis(t), newTestLogger())
+			if err != nil {
+				t.Fatal(err)
+			}
+			token := tt.token(t, s)
+
+			claims, err := s.Consume(ctx, tt.purpose, token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Consume error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if claims.Subject != user.UUID || claims.Email != tt.wantEmail {
+				t.Fatalf("Consume claims = %s/%s, want %s/%s", claims.Subject, claims.Email, user.UUID, tt.wantEmail)
+			}
+
+			// Links are single-use
+			if _, err := s.Consume(ctx, tt.purpose, token); !errors.Is(err, ErrInvalidEmailToken) {
+				t.Fatalf("second Consume error = %v, want ErrInvalidEmailToken", err)
+			}
+		})
+	}
+}
+
+// TestEmailTokenConsumeOnce checks that a link redeemed concurrently is accepted once
+func TestEmailTokenConsumeOnce(t *testing.T) {
+	s, err := NewEmailTokenService(newTestRedis(t), newTestLogger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := mustIssue(t, s, EmailTokenPasswordReset, "a@example.com")
+
+	const attempts = 10
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		go func() {
+			_, err := s.Consume(context.Background(), EmailTokenPasswordReset, token)
+			results <- err
+		}()
+	}
+	accepted := 0
+	for i := 0; i < attempts; i++ {
+		if err := <-results; err == nil {
+			accepted++
+		} else if !errors.Is(err, ErrInvalidEmailToken) {
+			t.Fatal(err)
+		}
+	}
+	if accepted != 1 {
+		t.Fatalf("token accepted %d times, want once", accepted)
+	}
+}
+
+func mustIssue(t *testing.T, s *EmailTokenService, purpose, email string) string {
+	t.Helper()
+	token, err := s.Issue(context.Background(), purpose, &models.User{UUID: "user-uuid"}, email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"quiz-api/config"
+	"quiz-api/dto"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"quiz-api/utils"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Join codes are read aloud and typed on phones, so the alphabet leaves out look-alike characters.
+// 8 characters from 31 give about 8.5e11 codes, out of reach of the rate-limited join endpoint.
+const (
+	joinCodeLength   = 8
+	joinCodeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+)
+
+var (
+	ErrQuizNotPublished = errors.New("quiz is not published")
+	ErrInvalidJoinCode  = errors.New("invalid or expired join code")
+	ErrGuestNotFound    = errors.New("guest session not found or expired")
+)
+
+// joinCodeRecord is stored in Redis under join_code:<code>
+type joinCodeRecord struct {
+	QuizUUID  string    `json:"quiz_uuid"`
+	CreatedBy string    `json:"created_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// guestRecord is stored in Redis under guest:<uuid> for as long as the guest token is valid
+type guestRecord struct {
+	QuizUUID string `json:"quiz_uuid"`
+	Nickname string `json:"nickname"`
+}
+
+// GuestService lets players join a published quiz with a short join code instead of an account.
+// Guests receive a token scoped to that quiz; their results are tracked so they can be purged separately.
+type GuestService struct {
+	redisClient       *config.RedisClient
+	guestRepo         *repositories.GuestRepository
+	scyllaRepo        *repositories.ScyllaDBRepository
+	signingKeyService *SigningKeyService
+	logger            *logrus.Logger
+	joinCodeTTL       time.Duration
+	guestTokenTTL     time.Duration
+}
+
+// NewGuestService initializes a new GuestService
+func NewGuestService(redisClient *config.RedisClient, guestRepo *repositories.GuestRepository, scyllaRepo *repositories.ScyllaDBRepository, signingKeyService *SigningKeyService, logger *logrus.Logger) *GuestService {
+	return &GuestService{
+		redisClient:       redisClient,
+		guestRepo:         guestRepo,
+		scyllaRepo:        scyllaRepo,
+		signingKeyService: signingKeyService,
+		logger:            logger,
+		joinCodeTTL:       utils.DurationFromEnv("JOIN_CODE_TTL", 2*time.Hour),
+		guestTokenTTL:     utils.GuestTokenTTL(),
+	}
+}
+
+func joinCodeKey(code string) string {
+	return "join_code:" + code
+}
+
+func guestKey(guestUUID string) string {
+	return "guest:" + guestUUID
+}
+
+// CreateJoinCode generates a join code for a published quiz
+func (s *GuestService) CreateJoinCode(ctx context.Context, quizUUID, creatorUUID string) (*dto.JoinCodeResponse, error) {
+	published, err := s.isPublished(quizUUID)
+	if err != nil {
+		return nil, err
+	}
+	if !published {
+		return nil, ErrQuizNotPublished
+	}
+
+	record := joinCodeRecord{QuizUUID: quizUUID, CreatedBy: creatorUUID, ExpiresAt: time.Now().Add(s.joinCodeTTL)}
+	for attempt := 0; attempt < 10; attempt++ {
+		code, err := generateJoinCode()
+		if err != nil {
+			return nil, err
+		}
+		created, err := s.redisClient.SetNX(ctx, joinCodeKey(code), record, s.joinCodeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store join code: %w", err)
+		}
+		if created {
+			return &dto.JoinCodeResponse{Code: code, QuizUUID: quizUUID, ExpiresAt: record.ExpiresAt}, nil
+		}
+	}
+	return nil, errors.New("failed to allocate a free join code, try again")
+}
+
+// RevokeJoinCode stops new guests from joining with the code; guests already in keep playing
+func (s *GuestService) RevokeJoinCode(ctx context.Context, code string) error {
+	code = normalizeJoinCode(code)
+	exists, err := s.redisClient.Exists(ctx, joinCodeKey(code))
+	if err != nil {
+		return fmt.Errorf("failed to load join code: %w", err)
+	}
+	if !exists {
+		return ErrInvalidJoinCode
+	}
+	return s.redisClient.Delete(ctx, joinCodeKey(code))
+}
+
+// Join exchanges a join code and nickname for a guest token
+func (s *GuestService) Join(ctx context.Context, code, nickname string) (*dto.GuestJoinResponse, error) {
+	code = normalizeJoinCode(code)
+	nickname = strings.TrimSpace(nickname)
+	if nickname == "" {
+		return nil, errors.New("nickname is required")
+	}
+
+	var record joinCodeRecord
+	if err := s.redisClient.Get(ctx, joinCodeKey(code), &record); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidJoinCode
+		}
+		return nil, fmt.Errorf("failed to load join code: %w", err)
+	}
+
+	// The quiz may have been unpublished since the code was issued; the code dies with it
+	published, err := s.isPublished(record.QuizUUID)
+	if err != nil {
+		return nil, err
+	}
+	if !published {
+		s.redisClient.Delete(ctx, joinCodeKey(code))
+		return nil, ErrInvalidJoinCode
+	}
+
+	guest := &models.GuestPlayer{
+		UUID:     uuid.New().String(),
+		QuizUUID: record.QuizUUID,
+		Nickname: nickname,
+		JoinCode: code,
+	}
+	if err := s.guestRepo.Create(guest); err != nil {
+		return nil, fmt.Errorf("failed to create guest: %w", err)
+	}
+
+	if err := s.redisClient.Set(ctx, guestKey(guest.UUID), guestRecord{QuizUUID: guest.QuizUUID, Nickname: nickname}, s.guestTokenTTL); err != nil {
+		return nil, fmt.Errorf("failed to store guest session: %w", err)
+	}
+
+	token, err := s.signingKeyService.Sign(jwt.MapClaims{
+		"guest":     true,
+		"user_uuid": guest.UUID,
+		"fullname":  nickname,
+		"quiz_uuid": guest.QuizUUID,
+		"exp":       time.Now().Add(s.guestTokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.GuestJoinResponse{
+		Token:     token,
+		UUID:      guest.UUID,
+		Nickname:  nickname,
+		QuizUUID:  guest.QuizUUID,
+		ExpiresIn: int64(s.guestTokenTTL.Seconds()),
+	}, nil
+}
+
+// ValidateGuest checks that the guest session still exists and belongs to the quiz
+func (s *GuestService) ValidateGuest(ctx context.Context, guestUUID, quizUUID string) error {
+	var record guestRecord
+	if err := s.redisClient.Get(ctx, guestKey(guestUUID), &record); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return ErrGuestNotFound
+		}
+		return fmt.Errorf("failed to load guest session: %w", err)
+	}
+	if record.QuizUUID != quizUUID {
+		return ErrGuestNotFound
+	}
+	return nil
+}
+
+// PurgeGuests removes the results, answers and logs of guests who joined before the given time,
+// for one quiz or for every quiz when quizUUID is empty. It returns how many guests were purged.
+func (s *GuestService) PurgeGuests(ctx context.Context, quizUUID string, before time.Time) (int, error) {
+	guests, err := s.guestRepo.FindForPurge(quizUUID, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to retrieve guests: %w", err)
+	}
+
+	purged := 0
+	for _, guest := range guests {
+		if err := s.purgeGuest(ctx, guest); err != nil {
+			s.logger.WithError(err).WithFields(logrus.Fields{"guest_uuid": guest.UUID, "quiz_uuid": guest.QuizUUID}).Error("Failed to purge guest")
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (s *GuestService) purgeGuest(ctx context.Context, guest models.GuestPlayer) error {
+	if err := deleteQuizResults(s.scyllaRepo, guest.UUID, guest.QuizUUID); err != nil {
+		return err
+	}
+	s.redisClient.Delete(ctx, guestKey(guest.UUID))
+	return s.guestRepo.Delete(guest.UUID)
+}
+
+// isPublished reports whether the quiz has been exported to ScyllaDB for play
+func (s *GuestService) isPublished(quizUUID string) (bool, error) {
+	records, err := s.scyllaRepo.SelectRecords("quizs", []string{"quiz_uuid"}, map[string]interface{}{"quiz_uuid": quizUUID}, "", 1)
+	if err != nil {
+		return false, fmt.Errorf("failed to check quiz: %w", err)
+	}
+	return len(records) > 0, nil
+}
+
+func generateJoinCode() (string, error) {
+	max := big.NewInt(int64(len(joinCodeAlphabet)))
+	code := make([]byte, joinCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate join code: %w", err)
+		}
+		code[i] = joinCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// normalizeJoinCode lets players type codes in any case and with spaces or dashes
+func normalizeJoinCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+		return r
+	}, code)
+}
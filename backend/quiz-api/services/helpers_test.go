@@ -0,0 +1,61 @@
+package services
+
+import (
+	"io"
+	"quiz-api/config"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestRedis returns a client of an in-memory Redis server stopped when the test ends
+func newTestRedis(t *testing.T) *config.RedisClient {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return &config.RedisClient{Client: client}
+}
+
+// newTestUserRepository returns a repository over a fresh in-memory database
+func newTestUserRepository(t *testing.T) *repositories.UserRepository {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens its own database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.User{}, &models.RecoveryCode{}); err != nil {
+		t.Fatal(err)
+	}
+	return repositories.NewUserRepository(db)
+}
+
+func newTestUser(t *testing.T, repo *repositories.UserRepository, username string) *models.User {
+	t.Helper()
+	user := &models.User{Username: username, FullName: username, Password: "hash"}
+	if err := repo.Create(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
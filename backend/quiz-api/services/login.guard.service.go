@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"quiz-api/config"
+	"quiz-api/utils"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	LoginErrorThrottled = "LOGIN_THROTTLED"
+	LoginErrorLocked    = "ACCOUNT_LOCKED"
+)
+
+// LoginBlockedError is returned when a login attempt is refused before the password is checked
+type LoginBlockedError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Code == LoginErrorLocked {
+		return fmt.Sprintf("too many failed login attempts, locked for %s", e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginGuardConfig holds the brute-force protection thresholds
+type LoginGuardConfig struct {
+	FailureWindow    time.Duration // How long failed attempts are remembered
+	UserDelayAfter   int           // Failures for one username before each further attempt is delayed
+	IPDelayAfter     int           // Failures from one IP before each further attempt is delayed
+	DelayBase        time.Duration // First delay, doubled on every further failure
+	DelayMax         time.Duration
+	UserLockoutAfter int // Failures for one username before it is locked
+	IPLockoutAfter   int // Failures from one IP before it is locked
+	LockoutDuration  time.Duration
+}
+
+// NewLoginGuardConfig reads the thresholds from the environment
+func NewLoginGuardConfig() LoginGuardConfig {
+	return LoginGuardConfig{
+		FailureWindow:    utils.DurationFromEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		UserDelayAfter:   utils.IntFromEnv("LOGIN_DELAY_AFTER", 3),
+		IPDelayAfter:     utils.IntFromEnv("LOGIN_IP_DELAY_AFTER", 15),
+		DelayBase:        utils.DurationFromEnv("LOGIN_DELAY_BASE", time.Second),
+		DelayMax:         utils.DurationFromEnv("LOGIN_DELAY_MAX", 30*time.Second),
+		UserLockoutAfter: utils.IntFromEnv("LOGIN_LOCKOUT_THRESHOLD", 10),
+		IPLockoutAfter:   utils.IntFromEnv("LOGIN_IP_LOCKOUT_THRESHOLD", 50),
+		LockoutDuration:  utils.DurationFromEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+	}
+}
+
+// LoginGuardService counts failed logins per username and per IP in Redis,
+// delays further attempts progressively and locks out after a threshold.
+type LoginGuardService struct {
+	redisClient *config.RedisClient
+	logger      *logrus.Logger
+	config      LoginGuardConfig
+}
+
+// NewLoginGuardService initializes a new LoginGuardService
+func NewLoginGuardService(redisClient *config.RedisClient, logger *logrus.Logger) *LoginGuardService {
+	return &LoginGuardService{redisClient: redisClient, logger: logger, config: NewLoginGuardConfig()}
+}
+
+type loginSubject struct {
+	kind       string
+	value      string
+	delayAfter int
+	lockAfter  int
+}
+
+func (s *LoginGuardService) subjects(username, ip string) []loginSubject {
+	return []loginSubject{
+		{kind: "user", value: strings.ToLower(username), delayAfter: s.config.UserDelayAfter, lockAfter: s.config.UserLockoutAfter},
+		{kind: "ip", value: ip, delayAfter: s.config.IPDelayAfter, lockAfter: s.config.IPLockoutAfter},
+	}
+}
+
+func loginFailuresKey(kind, value string) string {
+	return "login_failures:" + kind + ":" + value
+}
+
+func loginDelayKey(kind, value string) string {
+	return "login_delay:" + kind + ":" + value
+}
+
+func loginLockKey(kind, value string) string {
+	return "login_lock:" + kind + ":" + value
+}
+
+// Check refuses the attempt while the username or IP is locked out or delayed
+func (s *LoginGuardService) Check(ctx context.Context, username, ip string) error {
+	for _, subject := range s.subjects(username, ip) {
+		if ttl, err := s.redisClient.TTL(ctx, loginLockKey(subject.kind, subject.value)); err == nil && ttl > 0 {
+			return &LoginBlockedError{Code: LoginErrorLocked, RetryAfter: ttl}
+		}
+		if ttl, err := s.redisClient.TTL(ctx, loginDelayKey(subject.kind, subject.value)); err == nil && ttl > 0 {
+			return &LoginBlockedError{Code: LoginErrorThrottled, RetryAfter: ttl}
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt, applies delays and lockouts, and logs it with the client IP
+func (s *LoginGuardService) RecordFailure(ctx context.Context, username, ip, reason string) {
+	fields := logrus.Fields{
+		"event":    "login_failed",
+		"username": username,
+		"ip":       ip,
+		"reason":   reason,
+	}
+
+	for _, subject := range s.subjects(username, ip) {
+		failures, err := s.redisClient.Incr(ctx, loginFailuresKey(subject.kind, subject.value), s.config.FailureWindow)
+		if err != nil {
+			s.logger.WithError(err).WithFields(fields).Error("Failed to record failed login attempt")
+			continue
+		}
+		fields[subject.kind+"_failures"] = failures
+
+		switch {
+		case int(failures) >= subject.lockAfter:
+			s.redisClient.Set(ctx, loginLockKey(subject.kind, subject.value), true, s.config.LockoutDuration)
+			fields[subject.kind+"_locked"] = true
+		case int(failures) >= subject.delayAfter:
+			delay := s.delayFor(int(failures) - subject.delayAfter)
+			s.redisClient.Set(ctx, loginDelayKey(subject.kind, subject.value), true, delay)
+		}
+	}
+
+	s.logger.WithFields(fields).Warn("Failed login attempt")
+}
+
+// RecordBlocked logs an attempt refused by Check so throttled traffic is visible as well
+func (s *LoginGuardService) RecordBlocked(username, ip string, err error) {
+	s.logger.WithFields(logrus.Fields{
+		"event":    "login_failed",
+		"username": username,
+		"ip":       ip,
+		"reason":   err.Error(),
+	}).Warn("Failed login attempt")
+}
+
+// RecordSuccess clears the failure history of the username
+func (s *LoginGuardService) RecordSuccess(ctx context.Context, username string) {
+	s.unlock(ctx, "user", strings.ToLower(username))
+}
+
+// UnlockUser lifts a lockout on a username on behalf of actorUUID, and records it
+func (s *LoginGuardService) UnlockUser(ctx context.Context, username, actorUUID string) error {
+	return s.unlockAudited(ctx, "user", strings.ToLower(username), actorUUID)
+}
+
+// UnlockIP lifts a lockout on a client IP on behalf of actorUUID, and records it
+func (s *LoginGuardService) UnlockIP(ctx context.Context, ip, actorUUID string) error {
+	return s.unlockAudited(ctx, "ip", ip, actorUUID)
+}
+
+func (s *LoginGuardService) unlockAudited(ctx context.Context, kind, value, actorUUID string) error {
+	fields := logrus.Fields{
+		"event":      "login_unlocked",
+		"kind":       kind,
+		"value":      value,
+		"actor_uuid": actorUUID,
+	}
+	if err := s.unlock(ctx, kind, value); err != nil {
+		s.logger.WithError(err).WithFields(fields).Error("Failed to lift login lockout")
+		return err
+	}
+	s.logger.WithFields(fields).Warn("Lifted login lockout")
+	return nil
+}
+
+// unlock removes the counters, delay and lockout of a username or IP
+func (s *LoginGuardService) unlock(ctx context.Context, kind, value string) error {
+	return s.redisClient.Delete(ctx,
+		loginFailuresKey(kind, value),
+		loginDelayKey(kind, value),
+		loginLockKey(kind, value),
+	)
+}
+
+func (s *LoginGuardService) delayFor(step int) time.Duration {
+	delay := time.Duration(float64(s.config.DelayBase) * math.Pow(2, float64(step)))
+	if delay <= 0 || delay > s.config.DelayMax {
+		return s.config.DelayMax
+	}
+	return delay
+}
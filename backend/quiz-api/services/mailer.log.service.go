@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// LogMailer logs the recipient and subject of every email and, when a directory is configured, writes the
+// full message there as an .eml file so links can be followed during development and asserted on in tests.
+// The body is never logged: it carries live verification and reset links.
+type LogMailer struct {
+	logger *logrus.Logger
+	dir    string
+}
+
+// NewLogMailer initializes a new LogMailer
+func NewLogMailer(logger *logrus.Logger, dir string) *LogMailer {
+	return &LogMailer{logger: logger, dir: dir}
+}
+
+// Send records the message instead of delivering it
+func (m *LogMailer) Send(ctx context.Context, message MailMessage) error {
+	m.logger.WithFields(logrus.Fields{
+		"event":   "mail_sent",
+		"to":      message.To,
+		"subject": message.Subject,
+	}).Info("Email recorded by log mailer")
+
+	if m.dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(m.dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), uuid.New().String())
+	if err := os.WriteFile(filepath.Join(m.dir, name), formatMail("no-reply@localhost", message), 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
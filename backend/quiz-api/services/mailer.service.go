@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	MailDriverSMTP = "smtp"
+	MailDriverLog  = "log"
+)
+
+// mailSendTimeout bounds the delivery of an email sent while handling a request
+const mailSendTimeout = 15 * time.Second
+
+// MailMessage is a plain text email
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails. MAIL_DRIVER selects the implementation.
+type Mailer interface {
+	Send(ctx context.Context, message MailMessage) error
+}
+
+// NewMailer returns the SMTP mailer when MAIL_DRIVER=smtp and the log mailer when MAIL_DRIVER=log.
+// Without a driver it falls back to the log mailer in debug and test mode and refuses to start in release mode,
+// since emails would silently never be delivered.
+func NewMailer(logger *logrus.Logger) Mailer {
+	switch driver := os.Getenv("MAIL_DRIVER"); driver {
+	case MailDriverSMTP:
+		return NewSMTPMailer(NewSMTPConfig())
+	case MailDriverLog:
+		if gin.Mode() == gin.ReleaseMode {
+			logger.Warn("MAIL_DRIVER=log in release mode: emails are recorded, not delivered")
+		}
+	default:
+		if gin.Mode() == gin.ReleaseMode {
+			logger.Fatalf("MAIL_DRIVER must be %q or %q in release mode, got %q", MailDriverSMTP, MailDriverLog, driver)
+		}
+		logger.Warnf("MAIL_DRIVER %q is not a delivering driver: emails are recorded by the log mailer, not delivered", driver)
+	}
+	return NewLogMailer(logger, os.Getenv("MAIL_LOG_DIR"))
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/sirupsen/logrus"
+)
+
+// NotificationEvent is what websocket-server consumes from the notifications topic
+// and delivers to a room or to every connection of a user
+type NotificationEvent struct {
+	Type      string      `json:"type"`
+	UserUUID  string      `json:"user_uuid,omitempty"`
+	Room      string      `json:"room,omitempty"`
+	Payload   interface{} `json:"payload,omitempty"`
+	Timestamp int64       `json:"ts"`
+}
+
+// NotificationService publishes events for connected clients through Kafka
+type NotificationService struct {
+	writer *kafka.Writer
+	topic  string
+	logger *logrus.Logger
+}
+
+// NewNotificationService initializes a NotificationService writing to NOTIFICATION_TOPIC
+func NewNotificationService(logger *logrus.Logger) *NotificationService {
+	broker := os.Getenv("KAFKA_BROKER")
+	username := os.Getenv("KAFKA_USERNAME")
+	password := os.Getenv("KAFKA_PASSWORD")
+	topic := os.Getenv("NOTIFICATION_TOPIC")
+	if topic == "" {
+		topic = "notifications"
+	}
+
+	if err := EnsureTopicExists(broker, topic, username, password, 1, 1); err != nil {
+		logger.WithFields(logrus.Fields{
+			"topic": topic,
+			"error": err.Error(),
+		}).Error("Failed to ensure topic exists")
+	}
+
+	return &NotificationService{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(broker),
+			Topic:    topic,
+			Balancer: &kafka.Hash{},
+			Transport: &kafka.Transport{
+				SASL: plain.Mechanism{
+					Username: username,
+					Password: password,
+				},
+			},
+		},
+		topic:  topic,
+		logger: logger,
+	}
+}
+
+// NotifyUser sends an event to every connection of a user
+func (s *NotificationService) NotifyUser(ctx context.Context, userUUID, eventType string, payload interface{}) error {
+	return s.publish(ctx, userUUID, NotificationEvent{Type: eventType, UserUUID: userUUID, Payload: payload})
+}
+
+// NotifyRoom sends an event to everyone in a websocket room
+func (s *NotificationService) NotifyRoom(ctx context.Context, room, eventType string, payload interface{}) error {
+	return s.publish(ctx, room, NotificationEvent{Type: eventType, Room: room, Payload: payload})
+}
+
+// publish keys events by their recipient so each recipient's events stay in order
+func (s *NotificationService) publish(ctx context.Context, key string, event NotificationEvent) error {
+	event.Timestamp = time.Now().UnixMilli()
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := s.writer.WriteMessages(ctx, kafka.Message{Key: []byte(key), Value: value}); err != nil {
+		return fmt.Errorf("failed to publish notification to topic %s: %w", s.topic, err)
+	}
+	return nil
+}
+
+// Close flushes pending notifications
+func (s *NotificationService) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,431 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"quiz-api/config"
+	"quiz-api/dto"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"quiz-api/utils"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	ErrOIDCDisabled     = errors.New("single sign-on is not configured")
+	ErrOIDCInvalidState = errors.New("invalid or expired sign-on state")
+	ErrIdentityLinked   = errors.New("this identity is already linked to another account")
+)
+
+// oidcDiscovery is the subset of the provider metadata document we rely on
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcState is kept in Redis between the authorization redirect and the callback
+type oidcState struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+	BindingHash  string `json:"binding_hash"`           // Hash of the secret the browser that started the sign-on must present
+	LinkUserID   uint   `json:"link_user_id,omitempty"` // Set when an existing user links a provider account
+}
+
+// oidcClaims are the ID token claims used to provision users
+type oidcClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+// OIDCService implements the OpenID Connect authorization code flow with PKCE
+type OIDCService struct {
+	config      *config.OIDCConfig
+	redisClient *config.RedisClient
+	userRepo    *repositories.UserRepository
+	twoFactor   *TwoFactorService
+	httpClient  *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	jwks      utils.JWKSet
+}
+
+// NewOIDCService initializes a new OIDCService
+func NewOIDCService(cfg *config.OIDCConfig, redisClient *config.RedisClient, userRepo *repositories.UserRepository, twoFactor *TwoFactorService) *OIDCService {
+	return &OIDCService{
+		config:      cfg,
+		redisClient: redisClient,
+		userRepo:    userRepo,
+		twoFactor:   twoFactor,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+// oidcBindingHash hashes the browser binding secret so Redis never holds it in clear
+func oidcBindingHash(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthorizationURL starts a sign-on and returns the provider URL to send the browser to, along with
+// a binding secret the browser keeps and presents on the callback, so a state issued to one browser
+// cannot be completed in another (login CSRF).
+// linkUserID is zero for a normal sign-on, or the current user when linking an account.
+func (s *OIDCService) AuthorizationURL(ctx context.Context, linkUserID uint) (string, string, error) {
+	if !s.config.Enabled() {
+		return "", "", ErrOIDCDisabled
+	}
+
+	discovery, err := s.getDiscovery(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := utils.GenerateSecret(24)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.GenerateSecret(24)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := utils.GenerateSecret(48)
+	if err != nil {
+		return "", "", err
+	}
+	binding, err := utils.GenerateSecret(24)
+	if err != nil {
+		return "", "", err
+	}
+
+	record := oidcState{CodeVerifier: codeVerifier, Nonce: nonce, BindingHash: oidcBindingHash(binding), LinkUserID: linkUserID}
+	if err := s.redisClient.Set(ctx, oidcStateKey(state), record, oidcStateTTL); err != nil {
+		return "", "", fmt.Errorf("failed to store sign-on state: %w", err)
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {s.config.ClientID},
+		"redirect_uri":          {s.config.RedirectURL},
+		"scope":                 {strings.Join(s.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), binding, nil
+}
+
+// Callback completes a sign-on: it redeems the code, verifies the ID token,
+// provisions or links the user and starts a normal session.
+// binding is the secret returned with the authorization URL. sessionUserID is the signed-in user
+// on a link callback and zero otherwise; it must match the user who started the sign-on.
+func (s *OIDCService) Callback(ctx context.Context, code, state, binding string, sessionUserID uint, userAgent, ip string) (*models.User, *dto.TokenResponse, error) {
+	if !s.config.Enabled() {
+		return nil, nil, ErrOIDCDisabled
+	}
+
+	var record oidcState
+	if err := s.redisClient.Get(ctx, oidcStateKey(state), &record); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil, ErrOIDCInvalidState
+		}
+		return nil, nil, fmt.Errorf("failed to load sign-on state: %w", err)
+	}
+	// A state can only be redeemed once
+	if err := s.redisClient.Delete(ctx, oidcStateKey(state)); err != nil {
+		return nil, nil, fmt.Errorf("failed to clear sign-on state: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(record.BindingHash), []byte(oidcBindingHash(binding))) != 1 {
+		return nil, nil, ErrOIDCInvalidState
+	}
+	if record.LinkUserID != sessionUserID {
+		return nil, nil, ErrOIDCInvalidState
+	}
+
+	rawIDToken, err := s.exchangeCode(ctx, code, record.CodeVerifier)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	claims, err := s.verifyIDToken(ctx, rawIDToken, record.Nonce)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.resolveUser(claims, record.LinkUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.IsDisabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	// The provider only replaces the password; a local second factor is still asked for
+	tokens, err := s.twoFactor.StartLogin(ctx, user, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+func (s *OIDCService) exchangeCode(ctx context.Context, code, codeVerifier string) (string, error) {
+	discovery, err := s.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {s.config.RedirectURL},
+		"client_id":     {s.config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+	if s.config.ClientSecret != "" {
+		form.Set("client_secret", s.config.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("authorization code rejected with status: %s", resp.Status)
+	}
+
+	var body struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if body.IDToken == "" {
+		return "", errors.New("token response does not contain an ID token")
+	}
+	return body.IDToken, nil
+}
+
+func (s *OIDCService) verifyIDToken(ctx context.Context, rawIDToken, nonce string) (*oidcClaims, error) {
+	discovery, err := s.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &oidcClaims{}
+	_, err = jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return s.getKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "EdDSA"}),
+		jwt.WithIssuer(discovery.Issuer), // Exactly as the provider spells it, trailing slash included
+		jwt.WithAudience(s.config.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if claims.Nonce != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid ID token: missing subject")
+	}
+	return claims, nil
+}
+
+func (s *OIDCService) resolveUser(claims *oidcClaims, linkUserID uint) (*models.User, error) {
+	user, err := s.userRepo.FindByIdentity(s.config.Issuer, claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up identity: %w", err)
+	}
+
+	if linkUserID != 0 {
+		if user != nil && user.ID != linkUserID {
+			return nil, ErrIdentityLinked
+		}
+		if user != nil {
+			return user, nil
+		}
+		identity := &models.UserIdentity{UserID: linkUserID, Issuer: s.config.Issuer, Subject: claims.Subject, Email: claims.Email}
+		if err := s.userRepo.CreateIdentity(identity); err != nil {
+			return nil, fmt.Errorf("failed to link identity: %w", err)
+		}
+		return s.userRepo.FindById(linkUserID)
+	}
+
+	if user != nil {
+		return user, nil
+	}
+	return s.provisionUser(claims)
+}
+
+var usernameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// provisionUser creates a learner account for a first-time sign-on.
+// The account gets an unusable random password, so it can only sign in through the provider.
+func (s *OIDCService) provisionUser(claims *oidcClaims) (*models.User, error) {
+	base := claims.PreferredUsername
+	if base == "" && claims.Email != "" {
+		base = strings.SplitN(claims.Email, "@", 2)[0]
+	}
+	base = usernameSanitizer.ReplaceAllString(base, "")
+	if base == "" {
+		base = "user"
+	}
+
+	username, err := s.uniqueValue(base, func(candidate string) (bool, error) {
+		existing, err := s.userRepo.FindByUsername(candidate)
+		return existing != nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fullNameBase := claims.Name
+	if fullNameBase == "" {
+		fullNameBase = username
+	}
+	fullName, err := s.uniqueValue(fullNameBase, s.userRepo.ExistsByFullName)
+	if err != nil {
+		return nil, err
+	}
+
+	randomPassword, err := utils.GenerateSecret(32)
+	if err != nil {
+		return nil, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username: username,
+		Password: string(hashedPassword),
+		FullName: fullName,
+		Role:     models.RoleLearner,
+	}
+	identity := &models.UserIdentity{Issuer: s.config.Issuer, Subject: claims.Subject, Email: claims.Email}
+	if err := s.userRepo.CreateWithIdentity(user, identity); err != nil {
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+	return user, nil
+}
+
+// uniqueValue appends a numeric suffix to base until taken reports it free
+func (s *OIDCService) uniqueValue(base string, taken func(string) (bool, error)) (string, error) {
+	candidate := base
+	for i := 2; i < 1000; i++ {
+		exists, err := taken(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%d", base, i)
+	}
+	return "", fmt.Errorf("could not find a free value for %s", base)
+}
+
+func (s *OIDCService) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.discovery != nil {
+		return s.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := s.getJSON(ctx, s.config.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to load provider metadata: %w", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != s.config.Issuer {
+		return nil, fmt.Errorf("provider issuer %s does not match OIDC_ISSUER", discovery.Issuer)
+	}
+	s.discovery = &discovery
+	return s.discovery, nil
+}
+
+// getKey returns the provider signing key, reloading the JWKS once when the key ID is unknown (key rotation)
+func (s *OIDCService) getKey(ctx context.Context, kid string) (interface{}, error) {
+	discovery, err := s.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.jwks.Find(kid)
+	if !ok {
+		var jwks utils.JWKSet
+		if err := s.getJSON(ctx, discovery.JWKSURI, &jwks); err != nil {
+			return nil, fmt.Errorf("failed to load provider keys: %w", err)
+		}
+		s.jwks = jwks
+		if key, ok = s.jwks.Find(kid); !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+	}
+	return key.PublicKey()
+}
+
+func (s *OIDCService) getJSON(ctx context.Context, url string, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"quiz-api/utils"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// quizLogDir is where the user_quiz_export consumer writes <user>-<quiz>.json files
+var quizLogDir = filepath.Join("static", "logs")
+
+var (
+	ErrDeletionNotConfirmed = errors.New("account deletion must be confirmed with your password")
+	ErrAdminDeletion        = errors.New("administrators must hand over the admin role before deleting their account")
+)
+
+// PersonalData is everything stored about a user, for data-subject access requests
+type PersonalData struct {
+	User        *models.User
+	Identities  []models.UserIdentity
+	APIKeys     []models.APIKey
+	Sessions    []*models.Session
+	QuizResults []map[string]interface{}
+	Answers     []map[string]interface{}
+	QuizLogs    map[string]json.RawMessage
+}
+
+// PersonalDataService exports and erases the personal data of a user across
+// Postgres, ScyllaDB, Redis sessions and the quiz log files.
+type PersonalDataService struct {
+	userRepo       *repositories.UserRepository
+	apiKeyRepo     *repositories.APIKeyRepository
+	scyllaRepo     *repositories.ScyllaDBRepository
+	sessionService *SessionService
+	tokenService   *TokenService
+	logger         *logrus.Logger
+}
+
+// NewPersonalDataService initializes a new PersonalDataService
+func NewPersonalDataService(userRepo *repositories.UserRepository, apiKeyRepo *repositories.APIKeyRepository, scyllaRepo *repositories.ScyllaDBRepository, sessionService *SessionService, tokenService *TokenService, logger *logrus.Logger) *PersonalDataService {
+	return &PersonalDataService{
+		userRepo:       userRepo,
+		apiKeyRepo:     apiKeyRepo,
+		scyllaRepo:     scyllaRepo,
+		sessionService: sessionService,
+		tokenService:   tokenService,
+		logger:         logger,
+	}
+}
+
+// Export collects the personal data of a user
+func (s *PersonalDataService) Export(ctx context.Context, userID uint) (*PersonalData, error) {
+	user, err := s.userRepo.FindById(userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	data := &PersonalData{User: user}
+	if data.Identities, err = s.userRepo.FindIdentities(userID); err != nil {
+		return nil, fmt.Errorf("failed to retrieve identities: %w", err)
+	}
+	if data.APIKeys, err = s.apiKeyRepo.GetByUser(userID); err != nil {
+		return nil, fmt.Errorf("failed to retrieve API keys: %w", err)
+	}
+	if data.Sessions, err = s.sessionService.List(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to retrieve sessions: %w", err)
+	}
+
+	conditions := map[string]interface{}{"user_uuid": user.UUID}
+	data.QuizResults, err = s.scyllaRepo.SelectRecords("user_quizs_by_user",
+		[]string{"quiz_uuid", "score", "fullname", "current_question_uuid", "created_at", "updated_at"}, conditions, "", 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve quiz results: %w", err)
+	}
+	data.Answers, err = s.scyllaRepo.SelectRecords("user_answers",
+		[]string{"quiz_uuid", "question_uuid", "answers", "answer_time"}, conditions, "", 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve answers: %w", err)
+	}
+
+	files, err := quizLogFiles(user.UUID)
+	if err != nil {
+		return nil, err
+	}
+	data.QuizLogs = make(map[string]json.RawMessage, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read quiz log: %w", err)
+		}
+		if !json.Valid(content) {
+			content, _ = json.Marshal(string(content))
+		}
+		data.QuizLogs[filepath.Base(file)] = content
+	}
+	return data, nil
+}
+
+// Delete erases the quiz results, answers and logs of a user, anonymizes the account row and
+// revokes every session. Accounts with a password must confirm with it; single sign-on
+// accounts without a known password confirm with "DELETE".
+func (s *PersonalDataService) Delete(ctx context.Context, userID uint, password, confirm string) error {
+	user, err := s.userRepo.FindById(userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.Role == models.RoleAdmin {
+		return ErrAdminDeletion
+	}
+
+	identities, err := s.userRepo.FindIdentities(userID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve identities: %w", err)
+	}
+	confirmed := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) == nil
+	if !confirmed && len(identities) > 0 && confirm == "DELETE" {
+		confirmed = true
+	}
+	if !confirmed {
+		return ErrDeletionNotConfirmed
+	}
+
+	if err := s.tokenService.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := deleteQuizResults(s.scyllaRepo, user.UUID, ""); err != nil {
+		return fmt.Errorf("failed to delete quiz results: %w", err)
+	}
+
+	randomPassword, err := utils.GenerateSecret(32)
+	if err != nil {
+		return err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	// Username and full name are unique, so the anonymized values are derived from the UUID
+	err = s.userRepo.Anonymize(user, map[string]interface{}{
+		"username":                "deleted-" + user.UUID,
+		"full_name":               "Deleted user " + user.UUID[:8],
+		"email":                   "",
+		"email_verified":          false,
+		"password":                string(hashedPassword),
+		"is_disabled":             true,
+		"password_reset_required": false,
+		"totp_secret":             "",
+		"totp_enabled":            false,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to anonymize account: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{"event": "account_deleted", "user_uuid": user.UUID}).Info("Personal data deleted")
+	return nil
+}
+
+// deleteQuizResults removes the user_quizs rows, user_answers rows and quiz log files of a player,
+// for one quiz or for every quiz when quizUUID is empty
+func deleteQuizResults(scyllaRepo *repositories.ScyllaDBRepository, userUUID, quizUUID string) error {
+	conditions := map[string]interface{}{"user_uuid": userUUID}
+	if quizUUID != "" {
+		conditions["quiz_uuid"] = quizUUID
+	}
+
+	// user_quizs is keyed by score, so look the rows up through the per-user view first
+	records, err := scyllaRepo.SelectRecords("user_quizs_by_user", []string{"quiz_uuid", "score"}, conditions, "", 0)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := scyllaRepo.DeleteRecord("user_quizs", map[string]interface{}{
+			"quiz_uuid": record["quiz_uuid"],
+			"score":     record["score"],
+			"user_uuid": userUUID,
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := scyllaRepo.DeleteRecord("user_answers", map[string]interface{}{"user_uuid": userUUID}); err != nil {
+		return err
+	}
+
+	pattern := "*"
+	if quizUUID != "" {
+		pattern = quizUUID
+	}
+	files, err := filepath.Glob(filepath.Join(quizLogDir, userUUID+"-"+pattern+".json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func quizLogFiles(userUUID string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(quizLogDir, userUUID+"-*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list quiz logs: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"quiz-api/models"
+	"quiz-api/repositories"
+
+	"gorm.io/gorm"
+)
+
+// Action is a permission that can be checked against the policy
+type Action string
+
+const (
+	ActionQuizCreate  Action = "quiz:create"
+	ActionQuizRead    Action = "quiz:read"
+	ActionQuizUpdate  Action = "quiz:update"
+	ActionQuizDelete  Action = "quiz:delete"
+	ActionQuizPublish Action = "quiz:publish"
+	ActionQuizShare   Action = "quiz:share"
+	ActionUserManage  Action = "user:manage"
+)
+
+// APIKeyScopes are the actions an API key can be granted
+var APIKeyScopes = []Action{
+	ActionQuizCreate,
+	ActionQuizRead,
+	ActionQuizUpdate,
+	ActionQuizDelete,
+	ActionQuizPublish,
+	ActionQuizShare,
+	ActionUserManage,
+}
+
+var (
+	ErrForbidden        = errors.New("you do not have permission to perform this action")
+	ErrResourceNotFound = errors.New("resource not found")
+)
+
+// rolePermissions lists the actions a role may perform on any quiz, regardless of ownership
+var rolePermissions = map[string]map[Action]bool{
+	models.RoleAuthor: {
+		ActionQuizCreate: true,
+	},
+	models.RoleReviewer: {
+		ActionQuizRead:    true,
+		ActionQuizPublish: true,
+	},
+}
+
+// ownerPermissions lists what the owner of a quiz may do with it
+var ownerPermissions = map[Action]bool{
+	ActionQuizRead:    true,
+	ActionQuizUpdate:  true,
+	ActionQuizDelete:  true,
+	ActionQuizPublish: true,
+	ActionQuizShare:   true,
+}
+
+// collaboratorPermissions lists what a collaborator may do with a shared quiz
+var collaboratorPermissions = map[string]map[Action]bool{
+	models.CollaboratorEditor: {
+		ActionQuizRead:   true,
+		ActionQuizUpdate: true,
+	},
+	models.CollaboratorViewer: {
+		ActionQuizRead: true,
+	},
+}
+
+// PolicyService decides whether a user may perform an action on a quiz.
+// The user's role is always read from the database so role changes apply immediately.
+type PolicyService struct {
+	userRepo     *repositories.UserRepository
+	quizRepo     *repositories.QuizRepository
+	questionRepo *repositories.QuestionRepository
+	answerRepo   *repositories.AnswerRepository
+}
+
+// NewPolicyService initializes a new PolicyService
+func NewPolicyService(userRepo *repositories.UserRepository, quizRepo *repositories.QuizRepository, questionRepo *repositories.QuestionRepository, answerRepo *repositories.AnswerRepository) *PolicyService {
+	return &PolicyService{userRepo: userRepo, quizRepo: quizRepo, questionRepo: questionRepo, answerRepo: answerRepo}
+}
+
+// CurrentUser loads the user making the request
+func (s *PolicyService) CurrentUser(userID uint) (*models.User, error) {
+	user, err := s.userRepo.FindById(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrForbidden
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if user.IsDisabled {
+		return nil, ErrForbidden
+	}
+	return user, nil
+}
+
+// IsAdmin reports whether the user currently holds the admin role
+func (s *PolicyService) IsAdmin(userID uint) (bool, error) {
+	user, err := s.CurrentUser(userID)
+	if err != nil {
+		return false, err
+	}
+	return user.Role == models.RoleAdmin, nil
+}
+
+// Authorize returns nil when the user may perform the action on the quiz.
+// quizUUID may be empty for actions that do not target an existing quiz.
+func (s *PolicyService) Authorize(userID uint, action Action, quizUUID string) error {
+	user, err := s.CurrentUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.Role == models.RoleAdmin || rolePermissions[user.Role][action] {
+		return nil
+	}
+	if quizUUID == "" {
+		return ErrForbidden
+	}
+
+	quiz, err := s.quizRepo.GetQuizOwner(quizUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrResourceNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load quiz: %w", err)
+	}
+
+	// Learners never manage quizzes, even ones they were once given
+	if user.Role == models.RoleLearner {
+		return ErrForbidden
+	}
+
+	if quiz.OwnerUUID == user.UUID && ownerPermissions[action] {
+		return nil
+	}
+
+	collaborator, err := s.quizRepo.GetCollaborator(quiz.UUID, user.UUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrForbidden
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load collaborator: %w", err)
+	}
+	if collaboratorPermissions[collaborator.Permission][action] {
+		return nil
+	}
+	return ErrForbidden
+}
+
+// QuizUUIDForQuestion resolves the quiz a question belongs to
+func (s *PolicyService) QuizUUIDForQuestion(questionUUID string) (string, error) {
+	question, err := s.questionRepo.GetQuestionByUUID(questionUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrResourceNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load question: %w", err)
+	}
+	return question.QuizUUID, nil
+}
+
+// QuizUUIDForAnswer resolves the quiz an answer belongs to
+func (s *PolicyService) QuizUUIDForAnswer(answerUUID string) (string, error) {
+	answer, err := s.answerRepo.GetAnswerByUUID(answerUUID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrResourceNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load answer: %w", err)
+	}
+	return s.QuizUUIDForQuestion(answer.QuestionUUID)
+}
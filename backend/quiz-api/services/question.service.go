@@ -36,6 +36,8 @@ func (s *QuestionService) GetQuestionsByQuiz(quizUUID string, page, limit int) (
 
 // UpdateQuestion updates an existing question
 func (s *QuestionService) UpdateQuestion(uuid string, updatedQuestion *models.Question) error {
+	// Questions cannot be moved between quizzes; access was checked against the current quiz
+	updatedQuestion.QuizUUID = ""
 	return s.questionRepo.UpdateQuestion(uuid, updatedQuestion)
 }
 
@@ -23,7 +23,7 @@ func NewQuizExportService(quizRepo *repositories.QuizRepository, scyllaRepo *rep
 	}
 }
 
-func (s *QuizExportService) ExportQuiz(quizUUID string, socketId string) (error, string) {
+func (s *QuizExportService) ExportQuiz(quizUUID string, userUUID string) (error, string) {
 	quiz, err := s.quizRepo.GetQuizByUUID(quizUUID)
 	if err != nil {
 		return fmt.Errorf("failed to fetch quiz: %v", err), ""
@@ -182,7 +182,7 @@ func (s *QuizExportService) ExportQuiz(quizUUID string, socketId string) (error,
 	return nil, quiz.Title
 }
 
-func (s *QuizExportService) RevokeQuiz(quizUUID string, socketId string) (string, error) {
+func (s *QuizExportService) RevokeQuiz(quizUUID string, userUUID string) (string, error) {
 	quizDir := filepath.Join("./static", quizUUID)
 	quizFile := filepath.Join(quizDir, "quiz.json")
 
@@ -17,19 +17,21 @@ import (
 // QuizService provides business logic for quizzes
 type QuizService struct {
 	quizRepo     *repositories.QuizRepository
+	userRepo     *repositories.UserRepository
 	kafkaService *KafkaService
 	scyllaRepo   *repositories.ScyllaDBRepository
 	redisClient  *config.RedisClient
 }
 
 // NewQuizService initializes a new QuizService
-func NewQuizService(quizRepo *repositories.QuizRepository, kafkaService *KafkaService, scyllaRepo *repositories.ScyllaDBRepository, redisClient *config.RedisClient) *QuizService {
-	return &QuizService{quizRepo: quizRepo, kafkaService: kafkaService, scyllaRepo: scyllaRepo, redisClient: redisClient}
+func NewQuizService(quizRepo *repositories.QuizRepository, userRepo *repositories.UserRepository, kafkaService *KafkaService, scyllaRepo *repositories.ScyllaDBRepository, redisClient *config.RedisClient) *QuizService {
+	return &QuizService{quizRepo: quizRepo, userRepo: userRepo, kafkaService: kafkaService, scyllaRepo: scyllaRepo, redisClient: redisClient}
 }
 
-// CreateQuiz creates a new quiz
-func (s *QuizService) CreateQuiz(quiz *models.Quiz) error {
+// CreateQuiz creates a new quiz owned by the given user
+func (s *QuizService) CreateQuiz(quiz *models.Quiz, ownerUUID string) error {
 	quiz.UUID = uuid.New().String()
+	quiz.OwnerUUID = ownerUUID
 	if err := s.quizRepo.CreateQuiz(quiz); err != nil {
 		return fmt.Errorf("failed to create quiz: %w", err)
 	}
@@ -45,8 +47,18 @@ func (s *QuizService) GetQuizByUUID(uuid string) (*models.Quiz, error) {
 	return quiz, nil
 }
 
-// UpdateQuiz updates an existing quiz
+// UpdateQuiz updates the editable fields of an existing quiz. Publishing, ownership and
+// collaborators have their own endpoints and policies, so they are never taken from the body.
 func (s *QuizService) UpdateQuiz(uuid string, updatedQuiz *models.Quiz) error {
+	existing, err := s.quizRepo.GetQuizOwner(uuid)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve quiz with UUID %s: %w", uuid, err)
+	}
+	updatedQuiz.UUID = uuid
+	updatedQuiz.OwnerUUID = existing.OwnerUUID
+	updatedQuiz.Questions = nil
+	updatedQuiz.Collaborators = nil
+
 	if err := s.quizRepo.UpdateQuiz(uuid, updatedQuiz); err != nil {
 		return fmt.Errorf("failed to update quiz with UUID %s: %w", uuid, err)
 	}
@@ -61,16 +73,54 @@ func (s *QuizService) DeleteQuiz(uuid string) error {
 	return nil
 }
 
-// QuizExport triggers an export of the quiz through Kafka
-func (s *QuizService) QuizExport(uuid string, socketID string) error {
+// GetCollaborators lists the collaborators of a quiz
+func (s *QuizService) GetCollaborators(quizUUID string) ([]models.QuizCollaborator, error) {
+	collaborators, err := s.quizRepo.GetCollaborators(quizUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve collaborators of quiz %s: %w", quizUUID, err)
+	}
+	return collaborators, nil
+}
+
+// SaveCollaborator shares a quiz with a user or changes their permission
+func (s *QuizService) SaveCollaborator(quizUUID, userUUID, permission string) (*models.QuizCollaborator, error) {
+	user, err := s.userRepo.FindByUUID(userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user %s: %w", userUUID, err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", userUUID)
+	}
+
+	collaborator := &models.QuizCollaborator{
+		QuizUUID:   quizUUID,
+		UserUUID:   userUUID,
+		Permission: permission,
+	}
+	if err := s.quizRepo.SaveCollaborator(collaborator); err != nil {
+		return nil, fmt.Errorf("failed to save collaborator: %w", err)
+	}
+	return collaborator, nil
+}
+
+// RemoveCollaborator stops sharing a quiz with a user
+func (s *QuizService) RemoveCollaborator(quizUUID, userUUID string) error {
+	if err := s.quizRepo.DeleteCollaborator(quizUUID, userUUID); err != nil {
+		return fmt.Errorf("failed to remove collaborator: %w", err)
+	}
+	return nil
+}
+
+// QuizExport triggers an export of the quiz through Kafka; the requesting user is notified when it ends
+func (s *QuizService) QuizExport(uuid string, userUUID string) error {
 	// Validate UUID format
 	if uuid == "" {
 		return fmt.Errorf("invalid UUID: cannot be empty")
 	}
 
 	// Attempt to publish the export message
-	s.kafkaService.PublishMessage("quiz_export", uuid, socketID)
-	// if err := s.kafkaService.PublishMessage("quiz_export", uuid, socketID); err != nil {
+	s.kafkaService.PublishMessage("quiz_export", uuid, userUUID)
+	// if err := s.kafkaService.PublishMessage("quiz_export", uuid, userUUID); err != nil {
 	// 	return fmt.Errorf("failed to publish quiz export message for UUID %s: %w", uuid, err)
 	// }
 
@@ -87,15 +137,15 @@ func (s *QuizService) GetQuizzesWithPagination(page, limit int) ([]models.Quiz,
 	return quizzes, total, nil
 }
 
-func (s *QuizService) RevokeQuiz(uuid string, socketID string) error {
+func (s *QuizService) RevokeQuiz(uuid string, userUUID string) error {
 	// Validate UUID format
 	if uuid == "" {
 		return fmt.Errorf("invalid UUID: cannot be empty")
 	}
 
 	// Attempt to publish the export message
-	s.kafkaService.PublishMessage("revoke_quiz", uuid, socketID)
-	// if err := s.kafkaService.PublishMessage("revoke_quiz", uuid, socketID); err != nil {
+	s.kafkaService.PublishMessage("revoke_quiz", uuid, userUUID)
+	// if err := s.kafkaService.PublishMessage("revoke_quiz", uuid, userUUID); err != nil {
 	// 	return fmt.Errorf("failed to publish quiz export message for UUID %s: %w", uuid, err)
 	// }
 
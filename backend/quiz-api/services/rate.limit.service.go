@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"quiz-api/config"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	RateLimitGroupAuth       = "auth"
+	RateLimitGroupGameplay   = "gameplay"
+	RateLimitGroupManagement = "management"
+	RateLimitGroupUnlock     = "unlock" // Lifting login lockouts, which would otherwise defeat the login guard
+)
+
+// RateLimitPolicy allows Limit requests per Window, refilled continuously
+type RateLimitPolicy struct {
+	Group  string
+	Limit  int
+	Window time.Duration
+}
+
+var defaultRateLimitPolicies = map[string]RateLimitPolicy{
+	RateLimitGroupAuth:       {Group: RateLimitGroupAuth, Limit: 20, Window: time.Minute},
+	RateLimitGroupGameplay:   {Group: RateLimitGroupGameplay, Limit: 60, Window: time.Minute},
+	RateLimitGroupManagement: {Group: RateLimitGroupManagement, Limit: 120, Window: time.Minute},
+	RateLimitGroupUnlock:     {Group: RateLimitGroupUnlock, Limit: 20, Window: time.Hour},
+}
+
+// NewRateLimitPolicy returns the policy of a route group. It can be overridden with
+// RATE_LIMIT_<GROUP>=<limit>/<window>, for example RATE_LIMIT_GAMEPLAY=60/1m.
+func NewRateLimitPolicy(group string) RateLimitPolicy {
+	policy, ok := defaultRateLimitPolicies[group]
+	if !ok {
+		policy = RateLimitPolicy{Group: group, Limit: 60, Window: time.Minute}
+	}
+
+	value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(group))
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return policy
+	}
+	limit, err := strconv.Atoi(parts[0])
+	if err != nil || limit <= 0 {
+		return policy
+	}
+	window, err := time.ParseDuration(parts[1])
+	if err != nil || window <= 0 {
+		return policy
+	}
+	return RateLimitPolicy{Group: group, Limit: limit, Window: window}
+}
+
+// RateLimitResult describes the state of a bucket after a request
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Time until the bucket is full again
+	RetryAfter time.Duration // Time until the next request is allowed, when refused
+}
+
+// tokenBucketScript refills and takes a token atomically, using the Redis clock so
+// every quiz-api instance shares the same bucket state.
+var tokenBucketScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local refill_per_ms = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * refill_per_ms)
+
+local allowed = 0
+local retry_after = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry_after = math.ceil((1 - tokens) / refill_per_ms)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(capacity / refill_per_ms))
+
+return {allowed, math.floor(tokens), retry_after, math.ceil((capacity - tokens) / refill_per_ms)}
+`)
+
+// RateLimitService implements a token bucket per client and route group in Redis
+type RateLimitService struct {
+	redisClient *config.RedisClient
+}
+
+// NewRateLimitService initializes a new RateLimitService
+func NewRateLimitService(redisClient *config.RedisClient) *RateLimitService {
+	return &RateLimitService{redisClient: redisClient}
+}
+
+// Allow takes a token from the bucket of the client for the given policy
+func (s *RateLimitService) Allow(ctx context.Context, policy RateLimitPolicy, clientKey string) (*RateLimitResult, error) {
+	key := fmt.Sprintf("rate_limit:%s:%s", policy.Group, clientKey)
+	refillPerMs := float64(policy.Limit) / float64(policy.Window.Milliseconds())
+
+	values, err := tokenBucketScript.Run(ctx, s.redisClient.Client, []string{key}, policy.Limit, refillPerMs).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply rate limit: %w", err)
+	}
+
+	return &RateLimitResult{
+		Allowed:    values[0] == 1,
+		Limit:      policy.Limit,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+		Reset:      time.Duration(values[3]) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quiz-api/config"
+	"quiz-api/models"
+	"quiz-api/utils"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// lastSeenResolution limits how often a session's last-seen time is written back to Redis
+const lastSeenResolution = time.Minute
+
+var ErrSessionNotFound = errors.New("session not found or expired")
+
+// SessionService keeps track of every active session of a user in Redis.
+// Each session is stored under session:<uuid> and indexed in the user_sessions:<user id> set.
+type SessionService struct {
+	redisClient *config.RedisClient
+}
+
+// NewSessionService initializes a new SessionService
+func NewSessionService(redisClient *config.RedisClient) *SessionService {
+	return &SessionService{redisClient: redisClient}
+}
+
+func sessionKey(sessionUUID string) string {
+	return "session:" + sessionUUID
+}
+
+func userSessionsKey(userID uint) string {
+	return "user_sessions:" + fmt.Sprint(userID)
+}
+
+// Create stores a new session and adds it to the user's session set
+func (s *SessionService) Create(ctx context.Context, session *models.Session) error {
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+
+	if err := s.redisClient.Set(ctx, sessionKey(session.UUID), session, utils.RefreshTokenTTL()); err != nil {
+		return fmt.Errorf("failed to store session: %w", err)
+	}
+	if err := s.redisClient.SAdd(ctx, userSessionsKey(session.UserID), session.UUID); err != nil {
+		return fmt.Errorf("failed to index session: %w", err)
+	}
+	return s.redisClient.Expire(ctx, userSessionsKey(session.UserID), utils.RefreshTokenTTL())
+}
+
+// Get returns a session of the user, or ErrSessionNotFound
+func (s *SessionService) Get(ctx context.Context, userID uint, sessionUUID string) (*models.Session, error) {
+	var session models.Session
+	err := s.redisClient.Get(ctx, sessionKey(sessionUUID), &session)
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if session.UserID != userID {
+		return nil, ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+// Validate checks that a session is still active and records the user's activity
+func (s *SessionService) Validate(ctx context.Context, userID uint, sessionUUID string) (*models.Session, error) {
+	session, err := s.Get(ctx, userID, sessionUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Since(session.LastSeenAt) >= lastSeenResolution {
+		session.LastSeenAt = time.Now()
+		ttl, err := s.redisClient.TTL(ctx, sessionKey(sessionUUID))
+		if err == nil && ttl > 0 {
+			s.redisClient.Set(ctx, sessionKey(sessionUUID), session, ttl)
+		}
+	}
+	return session, nil
+}
+
+// Extend pushes back the expiry of a session, used when its refresh token is rotated
+func (s *SessionService) Extend(ctx context.Context, userID uint, sessionUUID string) error {
+	if err := s.redisClient.Expire(ctx, sessionKey(sessionUUID), utils.RefreshTokenTTL()); err != nil {
+		return fmt.Errorf("failed to extend session: %w", err)
+	}
+	return s.redisClient.Expire(ctx, userSessionsKey(userID), utils.RefreshTokenTTL())
+}
+
+// List returns the active sessions of a user, most recently used first
+func (s *SessionService) List(ctx context.Context, userID uint) ([]*models.Session, error) {
+	sessionUUIDs, err := s.redisClient.SMembers(ctx, userSessionsKey(userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := make([]*models.Session, 0, len(sessionUUIDs))
+	for _, sessionUUID := range sessionUUIDs {
+		session, err := s.Get(ctx, userID, sessionUUID)
+		if errors.Is(err, ErrSessionNotFound) {
+			// The session expired on its own; drop it from the index
+			s.redisClient.SRem(ctx, userSessionsKey(userID), sessionUUID)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+// Delete removes a session record and its index entry
+func (s *SessionService) Delete(ctx context.Context, userID uint, sessionUUID string) error {
+	if err := s.redisClient.Delete(ctx, sessionKey(sessionUUID)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return s.redisClient.SRem(ctx, userSessionsKey(userID), sessionUUID)
+}
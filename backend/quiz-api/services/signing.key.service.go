@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"quiz-api/config"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"quiz-api/utils"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	SigningAlgRS256 = "RS256"
+	SigningAlgEdDSA = "EdDSA"
+
+	signingKeyRotationLock = "signing_key_rotation"
+	// signingKeyReloadBackoff limits how often an unknown kid triggers a reload
+	signingKeyReloadBackoff = 10 * time.Second
+)
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// SigningKeyConfig holds the token signing settings
+type SigningKeyConfig struct {
+	Algorithm        string        // JWT_SIGNING_ALG, RS256 or EdDSA
+	RotationInterval time.Duration // How long a key signs before a new one replaces it
+	RefreshInterval  time.Duration // How often each instance reloads keys and checks whether rotation is due
+	LegacySecret     string        // JWT_SECRET; HS256 tokens issued before the upgrade are accepted with it...
+	LegacyUntil      time.Time     // ...until JWT_LEGACY_ACCEPT_UNTIL (RFC 3339); unset means never
+}
+
+// NewSigningKeyConfig reads the signing settings from the environment
+func NewSigningKeyConfig() SigningKeyConfig {
+	algorithm := os.Getenv("JWT_SIGNING_ALG")
+	if algorithm != SigningAlgEdDSA {
+		algorithm = SigningAlgRS256
+	}
+	return SigningKeyConfig{
+		Algorithm:        algorithm,
+		RotationInterval: utils.DurationFromEnv("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+		RefreshInterval:  utils.DurationFromEnv("JWT_KEY_REFRESH_INTERVAL", time.Minute),
+		LegacySecret:     os.Getenv("JWT_SECRET"),
+		LegacyUntil:      utils.TimeFromEnv("JWT_LEGACY_ACCEPT_UNTIL"),
+	}
+}
+
+// legacyAccepted reports whether kid-less HS256 tokens are still within their migration window
+func (c SigningKeyConfig) legacyAccepted() bool {
+	return c.LegacySecret != "" && time.Now().Before(c.LegacyUntil)
+}
+
+type signingKey struct {
+	kid        string
+	algorithm  string
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+	createdAt  time.Time
+}
+
+// SigningKeyService signs access tokens with asymmetric keys stored in Postgres and
+// publishes their public halves as a JWKS, so other services verify tokens without a secret.
+// Keys are rotated on a schedule; retired keys stay valid until the tokens they signed expire.
+type SigningKeyService struct {
+	repo        *repositories.SigningKeyRepository
+	redisClient *config.RedisClient
+	logger      *logrus.Logger
+	config      SigningKeyConfig
+
+	mu         sync.RWMutex
+	current    *signingKey
+	keys       map[string]*signingKey
+	jwks       utils.JWKSet
+	lastReload time.Time
+}
+
+// NewSigningKeyService loads the signing keys, creating the first one when none exists
+func NewSigningKeyService(repo *repositories.SigningKeyRepository, redisClient *config.RedisClient, logger *logrus.Logger) (*SigningKeyService, error) {
+	s := &SigningKeyService{
+		repo:        repo,
+		redisClient: redisClient,
+		logger:      logger,
+		config:      NewSigningKeyConfig(),
+		keys:        map[string]*signingKey{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := s.reload(); err != nil {
+		return nil, err
+	}
+	if err := s.rotateIfDue(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run reloads the keys and rotates them when due until ctx is cancelled
+func (s *SigningKeyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.reload(); err != nil {
+				s.logger.WithError(err).Error("Failed to reload signing keys")
+				continue
+			}
+			if err := s.rotateIfDue(ctx); err != nil {
+				s.logger.WithError(err).Error("Failed to rotate signing key")
+			}
+		}
+	}
+}
+
+// Sign signs claims with the current key and sets its kid header
+func (s *SigningKeyService) Sign(claims jwt.Claims) (string, error) {
+	s.mu.RLock()
+	current := s.current
+	s.mu.RUnlock()
+
+	if current == nil {
+		return "", errors.New("no signing key available")
+	}
+	// Retired keys are only published until the longest-lived token they signed has expired
+	exp, err := expirationTime(claims)
+	if err != nil || exp == nil || time.Until(exp.Time) > utils.SignedTokenTTL() {
+		return "", errors.New("signed tokens must expire within the signed token TTL")
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(current.algorithm), claims)
+	token.Header["kid"] = current.kid
+	return token.SignedString(current.privateKey)
+}
+
+// expirationTime reads the exp claim as it will be encoded. jwt.MapClaims only decodes
+// the float64 and json.Number values JSON parsing produces, not the integers callers set.
+func expirationTime(claims jwt.Claims) (*jwt.NumericDate, error) {
+	data, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	var registered jwt.RegisteredClaims
+	if err := json.Unmarshal(data, &registered); err != nil {
+		return nil, err
+	}
+	return registered.ExpiresAt, nil
+}
+
+// Keyfunc returns the key that verifies token, for use with jwt.Parse
+func (s *SigningKeyService) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if s.config.legacyAccepted() && token.Method == jwt.SigningMethodHS256 {
+			return []byte(s.config.LegacySecret), nil
+		}
+		return nil, ErrUnknownSigningKey
+	}
+
+	key := s.find(kid)
+	if key == nil {
+		// Another instance may have rotated since the last reload
+		s.mu.RLock()
+		stale := time.Since(s.lastReload) > signingKeyReloadBackoff
+		s.mu.RUnlock()
+		if stale {
+			if err := s.reload(); err != nil {
+				return nil, err
+			}
+			key = s.find(kid)
+		}
+	}
+	if key == nil || key.algorithm != token.Method.Alg() {
+		return nil, ErrUnknownSigningKey
+	}
+	return key.publicKey, nil
+}
+
+// ValidMethods lists the signing algorithms accepted on incoming tokens
+func (s *SigningKeyService) ValidMethods() []string {
+	methods := []string{SigningAlgRS256, SigningAlgEdDSA}
+	if s.config.legacyAccepted() {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	return methods
+}
+
+// JWKS returns the public keys of every key that may have signed a still valid token
+func (s *SigningKeyService) JWKS() utils.JWKSet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.jwks
+}
+
+func (s *SigningKeyService) find(kid string) *signingKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.keys[kid]
+}
+
+// reload replaces the in-memory keys with the valid keys from the database
+func (s *SigningKeyService) reload() error {
+	records, err := s.repo.GetValid(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to load signing keys: %w", err)
+	}
+
+	var current *signingKey
+	keys := make(map[string]*signingKey, len(records))
+	jwks := utils.JWKSet{Keys: make([]utils.JWK, 0, len(records))}
+	for _, record := range records {
+		key, err := parseSigningKey(record)
+		if err != nil {
+			s.logger.WithError(err).WithField("kid", record.Kid).Error("Skipping invalid signing key")
+			continue
+		}
+		jwk, err := utils.NewJWK(key.kid, key.algorithm, key.publicKey)
+		if err != nil {
+			return err
+		}
+		keys[key.kid] = key
+		jwks.Keys = append(jwks.Keys, jwk)
+		// Records are ordered newest first
+		if current == nil && record.RetiredAt == nil {
+			current = key
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = current
+	s.keys = keys
+	s.jwks = jwks
+	s.lastReload = time.Now()
+	return nil
+}
+
+// rotateIfDue creates a new key when there is none, the current one is older than the rotation
+// interval or uses another algorithm. A Redis lock keeps instances from rotating concurrently.
+func (s *SigningKeyService) rotateIfDue(ctx context.Context) error {
+	if !s.rotationDue() {
+		return nil
+	}
+
+	acquired, err := s.redisClient.SetNX(ctx, signingKeyRotationLock, true, 30*time.Second)
+	if err != nil {
+		return fmt.Errorf("failed to acquire rotation lock: %w", err)
+	}
+	if !acquired {
+		return nil
+	}
+	defer s.redisClient.Delete(ctx, signingKeyRotationLock)
+
+	// Another instance may have rotated while we waited for the lock
+	if err := s.reload(); err != nil {
+		return err
+	}
+	if !s.rotationDue() {
+		return nil
+	}
+	return s.Rotate()
+}
+
+func (s *SigningKeyService) rotationDue() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current == nil ||
+		s.current.algorithm != s.config.Algorithm ||
+		time.Since(s.current.createdAt) >= s.config.RotationInterval
+}
+
+// Rotate generates a new signing key and retires the current one. The retired key
+// keeps verifying tokens until the longest-lived token it signed, access or guest
+// token, has expired, with a minute of clock skew.
+func (s *SigningKeyService) Rotate() error {
+	privateKey, err := generatePrivateKey(s.config.Algorithm)
+	if err != nil {
+		return err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode signing key: %w", err)
+	}
+
+	now := time.Now()
+	record := &models.SigningKey{
+		Kid:        uuid.New().String(),
+		Algorithm:  s.config.Algorithm,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}
+	if err := s.repo.Rotate(record, now, now.Add(utils.SignedTokenTTL()+time.Minute)); err != nil {
+		return fmt.Errorf("failed to store signing key: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{"kid": record.Kid, "algorithm": record.Algorithm}).Info("Rotated token signing key")
+	return s.reload()
+}
+
+func generatePrivateKey(algorithm string) (crypto.PrivateKey, error) {
+	switch algorithm {
+	case SigningAlgEdDSA:
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		return privateKey, err
+	case SigningAlgRS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+	return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+}
+
+func parseSigningKey(record models.SigningKey) (*signingKey, error) {
+	block, _ := pem.Decode([]byte(record.PrivateKey))
+	if block == nil {
+		return nil, errors.New("invalid PEM block")
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	return &signingKey{
+		kid:        record.Kid,
+		algorithm:  record.Algorithm,
+		privateKey: privateKey,
+		publicKey:  signer.Public(),
+		createdAt:  record.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quiz-api/config"
+	"quiz-api/dto"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"quiz-api/utils"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, session revoked")
+)
+
+// refreshTokenRecord is stored in Redis under the hash of each issued refresh token
+type refreshTokenRecord struct {
+	UserID uint   `json:"user_id"`
+	Family string `json:"family"`
+}
+
+// TokenService issues access tokens and rotates refresh tokens.
+// Every login starts a session whose UUID identifies the token family; each refresh
+// replaces the presented token with a new one of the same family. Presenting a
+// token that was already rotated revokes the whole family.
+type TokenService struct {
+	redisClient       *config.RedisClient
+	userRepo          *repositories.UserRepository
+	sessionService    *SessionService
+	signingKeyService *SigningKeyService
+}
+
+// NewTokenService initializes a new TokenService
+func NewTokenService(redisClient *config.RedisClient, userRepo *repositories.UserRepository, sessionService *SessionService, signingKeyService *SigningKeyService) *TokenService {
+	return &TokenService{redisClient: redisClient, userRepo: userRepo, sessionService: sessionService, signingKeyService: signingKeyService}
+}
+
+func refreshTokenKey(hash string) string {
+	return "refresh_token:" + hash
+}
+
+func refreshTokenUsedKey(hash string) string {
+	return "refresh_token_used:" + hash
+}
+
+func refreshFamilyKey(family string) string {
+	return "refresh_family:" + family
+}
+
+// IssueTokens starts a new session for the user and returns its first token pair
+func (s *TokenService) IssueTokens(ctx context.Context, user *models.User, userAgent, ip string) (*dto.TokenResponse, error) {
+	session := &models.Session{
+		UUID:      uuid.New().String(),
+		UserID:    user.ID,
+		UserAgent: userAgent,
+		IP:        ip,
+	}
+	if err := s.sessionService.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return s.issuePair(ctx, user, session.UUID)
+}
+
+// Refresh exchanges a refresh token for a new token pair of the same family
+func (s *TokenService) Refresh(ctx context.Context, refreshToken string) (*models.User, *dto.TokenResponse, error) {
+	hash := utils.HashToken(refreshToken)
+
+	var record refreshTokenRecord
+	if err := s.redisClient.Get(ctx, refreshTokenKey(hash), &record); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, fmt.Errorf("failed to load refresh token: %w", err)
+	}
+
+	if _, err := s.sessionService.Get(ctx, record.UserID, record.Family); err != nil {
+		if errors.Is(err, ErrSessionNotFound) {
+			return nil, nil, ErrInvalidRefreshToken
+		}
+		return nil, nil, err
+	}
+
+	firstUse, err := s.redisClient.SetNX(ctx, refreshTokenUsedKey(hash), true, utils.RefreshTokenTTL())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to mark refresh token as used: %w", err)
+	}
+	if !firstUse {
+		if err := s.RevokeFamily(ctx, record.UserID, record.Family); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrRefreshTokenReused
+	}
+
+	user, err := s.userRepo.FindById(record.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if user.IsDisabled {
+		if err := s.RevokeFamily(ctx, record.UserID, record.Family); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.sessionService.Extend(ctx, user.ID, record.Family); err != nil {
+		return nil, nil, err
+	}
+
+	tokens, err := s.issuePair(ctx, user, record.Family)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// RevokeFamily invalidates every refresh token of a family and ends its session
+func (s *TokenService) RevokeFamily(ctx context.Context, userID uint, family string) error {
+	hashes, err := s.redisClient.SMembers(ctx, refreshFamilyKey(family))
+	if err != nil {
+		return fmt.Errorf("failed to load token family: %w", err)
+	}
+
+	keys := []string{refreshFamilyKey(family)}
+	for _, hash := range hashes {
+		keys = append(keys, refreshTokenKey(hash))
+	}
+	if err := s.redisClient.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to revoke token family: %w", err)
+	}
+
+	return s.sessionService.Delete(ctx, userID, family)
+}
+
+// RevokeAll ends every session of a user
+func (s *TokenService) RevokeAll(ctx context.Context, userID uint) error {
+	sessions, err := s.sessionService.List(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err := s.RevokeFamily(ctx, userID, session.UUID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TokenService) issuePair(ctx context.Context, user *models.User, family string) (*dto.TokenResponse, error) {
+	accessToken, err := s.signingKeyService.Sign(utils.TokenClaims(user, family))
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := utils.HashToken(refreshToken)
+	record := refreshTokenRecord{UserID: user.ID, Family: family}
+	if err := s.redisClient.Set(ctx, refreshTokenKey(hash), record, utils.RefreshTokenTTL()); err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	if err := s.redisClient.SAdd(ctx, refreshFamilyKey(family), hash); err != nil {
+		return nil, fmt.Errorf("failed to track refresh token: %w", err)
+	}
+	if err := s.redisClient.Expire(ctx, refreshFamilyKey(family), utils.RefreshTokenTTL()); err != nil {
+		return nil, fmt.Errorf("failed to track refresh token: %w", err)
+	}
+
+	return &dto.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"quiz-api/dto"
+	"quiz-api/models"
+	"testing"
+)
+
+func newTestTokenService(t *testing.T) (*TokenService, *models.User) {
+	t.Helper()
+	privateKey, err := generatePrivateKey(SigningAlgEdDSA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingKeys := &SigningKeyService{
+		current: &signingKey{kid: "test", algorithm: SigningAlgEdDSA, privateKey: privateKey},
+		keys:    map[string]*signingKey{},
+	}
+
+	redisClient := newTestRedis(t)
+	userRepo := newTestUserRepository(t)
+	user := newTestUser(t, userRepo, "alice")
+	return NewTokenService(redisClient, userRepo, NewSessionService(redisClient), signingKeys), user
+}
+
+func TestRefreshReuseDetection(t *testing.T) {
+	tests := []struct {
+		name string
+		// present returns the refresh token to present after login returned first
+		present     func(t *testing.T, s *TokenService, user *models.User, first *dto.TokenResponse) string
+		wantErr     error
+		wantRevoked bool // Whether the login's session and every token of its family are gone afterwards
+	}{
+		{
+			name: "fresh token",
+			present: func(t *testing.T, s *TokenService, user *models.User, first *dto.TokenResponse) string {
+				return first.RefreshToken
+			},
+		},
+		{
+			name: "rotated token",
+			present: func(t *testing.T, s *TokenService, user *models.User, first *dto.TokenResponse) string {
+				_, second := mustRefresh(t, s, first.RefreshToken)
+				return second.RefreshToken
+			},
+		},
+		{
+			name: "token replayed after rotation",
+			present: func(t *testing.T, s *TokenService, user *models.User, first *dto.TokenResponse) string {
+				mustRefresh(t, s, first.RefreshToken)
+				return first.RefreshToken
+			},
+			wantErr:     ErrRefreshTokenReused,
+			wantRevoked: true,
+		},
+		{
+			name: "older token replayed after several rotations",
+			present: func(t *testing.T, s *TokenService, user *models.User, first *dto.TokenResponse) string {
+				_, second := mustRefresh(t, s, first.RefreshToken)
+				mustRefresh(t, s, second.RefreshToken)
+				return second.RefreshToken
+			},
+			wantErr:     ErrRefreshTokenReused,
+			wantRevoked: true,
+		},
+		{
+			name: "unknown token",
+			present: func(t *testing.T, s *TokenService, user *models.User, first *dto.TokenResponse) string {
+				return "unknown"
+			},
+			wantErr: ErrInvalidRefreshToken,
+		},
+		{
+			name: "token of a revoked session",
+			present: func(t *testing.T, s *TokenService, user *models.User, first *dto.TokenResponse) string {
+				if err := s.RevokeAll(context.Background(), user.ID); err != nil {
+					t.Fatal(err)
+				}
+				return first.RefreshToken
+			},
+			wantErr:     ErrInvalidRefreshToken,
+			wantRevoked: true,
+		},
+		{
+			name: "token of a disabled user",
+			present: func(t *testing.T, s *TokenService, user *models.User, first *dto.TokenResponse) string {
+				if err := s.userRepo.DB.Model(user).Update("is_disabled", true).Error; err != nil {
+					t.Fatal(err)
+				}
+				return first.RefreshToken
+			},
+			wantErr:     ErrInvalidRefreshToken,
+			wantRevoked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, user := newTestTokenService(t)
+			first, err := s.IssueTokens(ctx, user, "test", "127.0.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sessions, err := s.sessionService.List(ctx, user.ID)
+			if err != nil || len(sessions) != 1 {
+				t.Fatalf("List = %d sessions, %v; want 1", len(sessions), err)
+			}
+			family := sessions[0].UUID
+
+			_, tokens, err := s.Refresh(ctx, tt.present(t, s, user, first))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Refresh error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && tokens.RefreshToken == "" {
+				t.Fatal("Refresh returned no refresh token")
+			}
+
+			_, err = s.sessionService.Get(ctx, user.ID, family)
+			if revoked := errors.Is(err, ErrSessionNotFound); revoked != tt.wantRevoked {
+				t.Fatalf("session revoked = %v, want %v", revoked, tt.wantRevoked)
+			}
+			if !tt.wantRevoked {
+				return
+			}
+			hashes, err := s.redisClient.SMembers(ctx, refreshFamilyKey(family))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(hashes) != 0 {
+				t.Fatalf("%d refresh tokens left in the revoked family", len(hashes))
+			}
+		})
+	}
+}
+
+// TestRefreshReuseRevokesNewestToken checks that the legitimate holder of the
+// newest token is logged out too once an older token of its family is replayed
+func TestRefreshReuseRevokesNewestToken(t *testing.T) {
+	ctx := context.Background()
+	s, user := newTestTokenService(t)
+	first, err := s.IssueTokens(ctx, user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, second := mustRefresh(t, s, first.RefreshToken)
+
+	if _, _, err := s.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("replayed Refresh error = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, _, err := s.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("newest Refresh error = %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func mustRefresh(t *testing.T, s *TokenService, refreshToken string) (*models.User, *dto.TokenResponse) {
+	t.Helper()
+	user, tokens, err := s.Refresh(context.Background(), refreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, tokens
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"os"
+	"quiz-api/config"
+	"quiz-api/dto"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"quiz-api/utils"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	totpEnrollmentTTL  = 10 * time.Minute
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// Codes from the previous and next time step are accepted to absorb clock drift
+	totpSkew = 1
+)
+
+var (
+	ErrTwoFactorEnabled          = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled       = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorRequired         = errors.New("two-factor authentication is required for admin accounts")
+	ErrNoPendingEnrollment       = errors.New("no pending two-factor enrolment, start again")
+	ErrInvalidTwoFactorCode      = errors.New("invalid two-factor code")
+	ErrInvalidTwoFactorChallenge = errors.New("invalid or expired two-factor challenge")
+)
+
+// TwoFactorChallengeError is returned instead of tokens when the password was correct
+// but the account still has to present a TOTP or recovery code
+type TwoFactorChallengeError struct {
+	Token     string
+	ExpiresIn int64
+}
+
+func (e *TwoFactorChallengeError) Error() string {
+	return "two-factor authentication required"
+}
+
+// twoFactorChallenge is kept in Redis between the password step and the code step
+type twoFactorChallenge struct {
+	UserID    uint   `json:"user_id"`
+	UserAgent string `json:"user_agent"`
+	IP        string `json:"ip"`
+}
+
+// TwoFactorService implements TOTP enrolment, recovery codes and the second login step
+type TwoFactorService struct {
+	userRepo     *repositories.UserRepository
+	redisClient  *config.RedisClient
+	tokenService *TokenService
+	loginGuard   *LoginGuardService
+	logger       *logrus.Logger
+	issuer       string
+	challengeTTL time.Duration
+	maxAttempts  int
+}
+
+func NewTwoFactorService(userRepo *repositories.UserRepository, redisClient *config.RedisClient, tokenService *TokenService, loginGuard *LoginGuardService, logger *logrus.Logger) *TwoFactorService {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Vocabulary Quiz"
+	}
+	return &TwoFactorService{
+		userRepo:     userRepo,
+		redisClient:  redisClient,
+		tokenService: tokenService,
+		loginGuard:   loginGuard,
+		logger:       logger,
+		issuer:       issuer,
+		challengeTTL: utils.DurationFromEnv("TWO_FACTOR_CHALLENGE_TTL", 5*time.Minute),
+		maxAttempts:  utils.IntFromEnv("TWO_FACTOR_MAX_ATTEMPTS", 5),
+	}
+}
+
+func totpEnrollmentKey(userID uint) string {
+	return fmt.Sprintf("totp_enrollment:%d", userID)
+}
+
+func totpUsedKey(userID uint, step int64) string {
+	return fmt.Sprintf("totp_used:%d:%d", userID, step)
+}
+
+func twoFactorChallengeKey(hash string) string {
+	return "two_factor_challenge:" + hash
+}
+
+func twoFactorAttemptsKey(hash string) string {
+	return "two_factor_attempts:" + hash
+}
+
+// StartLogin issues tokens for a user whose first factor has been checked, or a
+// TwoFactorChallengeError carrying a challenge token when 2FA is enabled
+func (s *TwoFactorService) StartLogin(ctx context.Context, user *models.User, userAgent, ip string) (*dto.TokenResponse, error) {
+	if !user.TOTPEnabled {
+		return s.tokenService.IssueTokens(ctx, user, userAgent, ip)
+	}
+
+	token, err := utils.GenerateSecret(32)
+	if err != nil {
+		return nil, err
+	}
+	challenge := twoFactorChallenge{UserID: user.ID, UserAgent: userAgent, IP: ip}
+	if err := s.redisClient.Set(ctx, twoFactorChallengeKey(utils.HashToken(token)), challenge, s.challengeTTL); err != nil {
+		return nil, fmt.Errorf("failed to store two-factor challenge: %w", err)
+	}
+	return nil, &TwoFactorChallengeError{Token: token, ExpiresIn: int64(s.challengeTTL.Seconds())}
+}
+
+// CompleteLogin redeems a challenge token with a TOTP or recovery code and starts the session.
+// Each challenge allows a limited number of attempts, and failures count towards the login lockout.
+func (s *TwoFactorService) CompleteLogin(ctx context.Context, challengeToken, code, ip string) (*models.User, *dto.TokenResponse, error) {
+	hash := utils.HashToken(challengeToken)
+
+	var challenge twoFactorChallenge
+	if err := s.redisClient.Get(ctx, twoFactorChallengeKey(hash), &challenge); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, nil, ErrInvalidTwoFactorChallenge
+		}
+		return nil, nil, fmt.Errorf("failed to load two-factor challenge: %w", err)
+	}
+
+	user, err := s.userRepo.FindById(challenge.UserID)
+	if err != nil {
+		return nil, nil, ErrInvalidTwoFactorChallenge
+	}
+	if err := s.loginGuard.Check(ctx, user.Username, ip); err != nil {
+		s.loginGuard.RecordBlocked(user.Username, ip, err)
+		return nil, nil, err
+	}
+
+	attempts, err := s.redisClient.Incr(ctx, twoFactorAttemptsKey(hash), s.challengeTTL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to count two-factor attempts: %w", err)
+	}
+	if int(attempts) > s.maxAttempts {
+		s.redisClient.Delete(ctx, twoFactorChallengeKey(hash), twoFactorAttemptsKey(hash))
+		return nil, nil, ErrInvalidTwoFactorChallenge
+	}
+
+	valid, err := s.verifyCode(ctx, user, code, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !valid {
+		s.loginGuard.RecordFailure(ctx, user.Username, ip, "invalid two-factor code")
+		return nil, nil, ErrInvalidTwoFactorCode
+	}
+
+	// A challenge can only be redeemed once, even by concurrent requests
+	deleted, err := s.redisClient.Client.Del(ctx, twoFactorChallengeKey(hash)).Result()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to clear two-factor challenge: %w", err)
+	}
+	if deleted == 0 {
+		return nil, nil, ErrInvalidTwoFactorChallenge
+	}
+	s.redisClient.Delete(ctx, twoFactorAttemptsKey(hash))
+	s.loginGuard.RecordSuccess(ctx, user.Username)
+
+	if user.IsDisabled {
+		return nil, nil, ErrAccountDisabled
+	}
+
+	tokens, err := s.tokenService.IssueTokens(ctx, user, challenge.UserAgent, challenge.IP)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, tokens, nil
+}
+
+// BeginEnrollment generates a new secret and keeps it pending until a code from it is confirmed
+func (s *TwoFactorService) BeginEnrollment(ctx context.Context, userID uint) (*dto.TwoFactorEnrollmentResponse, error) {
+	user, err := s.userRepo.FindById(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redisClient.Set(ctx, totpEnrollmentKey(userID), secret, totpEnrollmentTTL); err != nil {
+		return nil, fmt.Errorf("failed to store pending enrolment: %w", err)
+	}
+
+	return &dto.TwoFactorEnrollmentResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(s.issuer, user.Username, secret),
+		ExpiresIn:       int64(totpEnrollmentTTL.Seconds()),
+	}, nil
+}
+
+// ConfirmEnrollment enables 2FA once the user proves their authenticator produces valid codes,
+// and returns the recovery codes; they are shown this one time only
+func (s *TwoFactorService) ConfirmEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.FindById(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	var secret string
+	if err := s.redisClient.Get(ctx, totpEnrollmentKey(userID), &secret); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrNoPendingEnrollment
+		}
+		return nil, fmt.Errorf("failed to load pending enrolment: %w", err)
+	}
+
+	user.TOTPSecret = secret
+	valid, err := s.verifyCode(ctx, user, code, false)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.EnableTwoFactor(userID, secret, hashes); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+	s.redisClient.Delete(ctx, totpEnrollmentKey(userID))
+
+	s.logger.WithFields(logrus.Fields{"event": "two_factor_enabled", "user_uuid": user.UUID}).Info("Two-factor authentication enabled")
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of a user after checking a current TOTP code
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.FindById(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	valid, err := s.verifyCode(ctx, user, code, false)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{"event": "recovery_codes_regenerated", "user_uuid": user.UUID}).Info("Recovery codes regenerated")
+	return codes, nil
+}
+
+// Disable turns 2FA off after checking the password and a TOTP or recovery code.
+// Admins cannot disable it while REQUIRE_ADMIN_2FA is set.
+func (s *TwoFactorService) Disable(ctx context.Context, userID uint, password, code string) error {
+	user, err := s.userRepo.FindById(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return errors.New("invalid credentials")
+	}
+
+	valid, err := s.verifyCode(ctx, user, code, true)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalidTwoFactorCode
+	}
+	if utils.TwoFactorRequired(user) {
+		return ErrTwoFactorRequired
+	}
+
+	if err := s.userRepo.DisableTwoFactor(userID); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	s.logger.WithFields(logrus.Fields{"event": "two_factor_disabled", "user_uuid": user.UUID}).Info("Two-factor authentication disabled")
+	return nil
+}
+
+// Reset turns 2FA off for a user who lost both their authenticator and recovery codes, and ends their sessions
+func (s *TwoFactorService) Reset(ctx context.Context, userUUID string) error {
+	user, err := s.userRepo.FindByUUID(userUUID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err := s.userRepo.DisableTwoFactor(user.ID); err != nil {
+		return fmt.Errorf("failed to reset two-factor authentication: %w", err)
+	}
+	if err := s.tokenService.RevokeAll(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.logger.WithFields(logrus.Fields{"event": "two_factor_reset", "user_uuid": user.UUID}).Warn("Two-factor authentication reset by an admin")
+	return nil
+}
+
+// RemainingRecoveryCodes returns how many unused recovery codes a user has
+func (s *TwoFactorService) RemainingRecoveryCodes(userID uint) (int64, error) {
+	return s.userRepo.CountRecoveryCodes(userID)
+}
+
+// verifyCode checks a TOTP code, or a recovery code when allowRecovery is set.
+// A TOTP code is accepted once; replaying it within its validity window fails.
+func (s *TwoFactorService) verifyCode(ctx context.Context, user *models.User, code string, allowRecovery bool) (bool, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, nil
+	}
+
+	if step, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now(), totpSkew); ok {
+		firstUse, err := s.redisClient.SetNX(ctx, totpUsedKey(user.ID, step), true, (2*totpSkew+2)*utils.TOTPPeriod)
+		if err != nil {
+			return false, fmt.Errorf("failed to record used code: %w", err)
+		}
+		return firstUse, nil
+	}
+
+	if !allowRecovery || len(code) == utils.TOTPDigits {
+		return false, nil
+	}
+	used, err := s.userRepo.UseRecoveryCode(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, fmt.Errorf("failed to check recovery code: %w", err)
+	}
+	if used {
+		s.logger.WithFields(logrus.Fields{"event": "recovery_code_used", "user_uuid": user.UUID}).Warn("Recovery code used")
+	}
+	return used, nil
+}
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes returns new codes formatted as xxxxx-xxxxx together with their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(buf))[:recoveryCodeLength]
+		codes[i] = raw[:recoveryCodeLength/2] + "-" + raw[recoveryCodeLength/2:]
+		hashes[i] = utils.HashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode ignores case, spaces and the separator so codes can be typed loosely
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
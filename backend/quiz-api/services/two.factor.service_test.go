@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"quiz-api/models"
+	"quiz-api/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestTwoFactorService(t *testing.T) (*TwoFactorService, *models.User, []string) {
+	t.Helper()
+	userRepo := newTestUserRepository(t)
+	user := newTestUser(t, userRepo, "alice")
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userRepo.DB.Model(user).Updates(map[string]interface{}{"totp_secret": secret, "totp_enabled": true}).Error; err != nil {
+		t.Fatal(err)
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userRepo.ReplaceRecoveryCodes(user.ID, hashes); err != nil {
+		t.Fatal(err)
+	}
+	return NewTwoFactorService(userRepo, newTestRedis(t), nil, nil, newTestLogger()), user, codes
+}
+
+func TestVerifyCode(t *testing.T) {
+	currentTOTP := func(t *testing.T, user *models.User, codes []string) string {
+		code, err := utils.TOTPCode(user.TOTPSecret, utils.TOTPStep(time.Now()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name          string
+		code          func(t *testing.T, user *models.User, codes []string) string
+		allowRecovery bool
+		want          bool
+		wantReplay    bool  // Result of presenting the same code a second time
+		wantRemaining int64 // Unused recovery codes left afterwards
+	}{
+		{
+			name:          "TOTP code is accepted once",
+			code:          currentTOTP,
+			want:          true,
+			wantRemaining: recoveryCodeCount,
+		},
+		{
+			name: "TOTP code outside the window",
+			code: func(t *testing.T, user *models.User, codes []string) string {
+				code, err := utils.TOTPCode(user.TOTPSecret, utils.TOTPStep(time.Now())-totpSkew-1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return code
+			},
+			allowRecovery: true,
+			wantRemaining: recoveryCodeCount,
+		},
+		{
+			name:          "recovery code is burnt on use",
+			code:          func(t *testing.T, user *models.User, codes []string) string { return codes[0] },
+			allowRecovery: true,
+			want:          true,
+			wantRemaining: recoveryCodeCount - 1,
+		},
+		{
+			name: "recovery code typed loosely",
+			code: func(t *testing.T, user *models.User, codes []string) string {
+				return " " + strings.ToUpper(strings.ReplaceAll(codes[1], "-", "")) + " "
+			},
+			allowRecovery: true,
+			want:          true,
+			wantRemaining: recoveryCodeCount - 1,
+		},
+		{
+			name:          "recovery code where only TOTP is allowed",
+			code:          func(t *testing.T, user *models.User, codes []string) string { return codes[0] },
+			wantRemaining: recoveryCodeCount,
+		},
+		{
+			name:          "unknown recovery code",
+			code:          func(t *testing.T, user *models.User, codes []string) string { return "aaaaa-aaaaa" },
+			allowRecovery: true,
+			wantRemaining: recoveryCodeCount,
+		},
+		{
+			name:          "empty code",
+			code:          func(t *testing.T, user *models.User, codes []string) string { return " " },
+			allowRecovery: true,
+			wantRemaining: recoveryCodeCount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, user, codes := newTestTwoFactorService(t)
+			user, err := s.userRepo.FindById(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			code := tt.code(t, user, codes)
+
+			ok, err := s.verifyCode(ctx, user, code, tt.allowRecovery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.want {
+				t.Fatalf("verifyCode = %v, want %v", ok, tt.want)
+			}
+			ok, err = s.verifyCode(ctx, user, code, tt.allowRecovery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ok != tt.wantReplay {
+				t.Fatalf("replayed verifyCode = %v, want %v", ok, tt.wantReplay)
+			}
+
+			remaining, err := s.RemainingRecoveryCodes(user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if remaining != tt.wantRemaining {
+				t.Fatalf("RemainingRecoveryCodes = %d, want %d", remaining, tt.wantRemaining)
+			}
+		})
+	}
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != recoveryCodeCount || len(hashes) != recoveryCodeCount {
+		t.Fatalf("got %d codes and %d hashes, want %d", len(codes), len(hashes), recoveryCodeCount)
+	}
+	seen := map[string]bool{}
+	for i, code := range codes {
+		if len(code) != recoveryCodeLength+1 || code[recoveryCodeLength/2] != '-' {
+			t.Errorf("code %q is not formatted as xxxxx-xxxxx", code)
+		}
+		if hashes[i] != utils.HashToken(normalizeRecoveryCode(code)) {
+			t.Errorf("hash of code %q does not match its normalized form", code)
+		}
+		if seen[code] {
+			t.Errorf("code %q generated twice", code)
+		}
+		seen[code] = true
+	}
+}
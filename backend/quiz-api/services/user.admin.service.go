@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"quiz-api/models"
+	"quiz-api/repositories"
+	"quiz-api/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserNotFound   = errors.New("user not found")
+	ErrSelfManagement = errors.New("administrators cannot change their own access")
+)
+
+// UserAdminService provides account management for administrators
+type UserAdminService struct {
+	userRepo     *repositories.UserRepository
+	tokenService *TokenService
+	loginGuard   *LoginGuardService
+}
+
+// NewUserAdminService initializes a new UserAdminService
+func NewUserAdminService(userRepo *repositories.UserRepository, tokenService *TokenService, loginGuard *LoginGuardService) *UserAdminService {
+	return &UserAdminService{userRepo: userRepo, tokenService: tokenService, loginGuard: loginGuard}
+}
+
+// SearchUsers retrieves paginated users matching query
+func (s *UserAdminService) SearchUsers(query string, page, limit int) ([]models.User, int64, error) {
+	offset := (page - 1) * limit
+	users, total, err := s.userRepo.SearchUsers(query, offset, limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	return users, total, nil
+}
+
+// GetUser retrieves a user by UUID
+func (s *UserAdminService) GetUser(userUUID string) (*models.User, error) {
+	user, err := s.userRepo.FindByUUID(userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// UpdateRole changes the role of a user; it applies to their next request
+func (s *UserAdminService) UpdateRole(actorUUID, userUUID, role string) (*models.User, error) {
+	if !models.IsValidRole(role) {
+		return nil, fmt.Errorf("invalid role %s", role)
+	}
+
+	user, err := s.GetUser(userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if user.UUID == actorUUID && role != models.RoleAdmin {
+		return nil, ErrSelfManagement
+	}
+
+	if err := s.userRepo.UpdateRole(user.ID, role); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+	user.Role = role
+	user.IsAdmin = role == models.RoleAdmin
+	return user, nil
+}
+
+// SetAdmin grants the admin role, or revokes it leaving the user a learner
+func (s *UserAdminService) SetAdmin(actorUUID, userUUID string, isAdmin bool) (*models.User, error) {
+	role := models.RoleLearner
+	if isAdmin {
+		role = models.RoleAdmin
+	}
+	return s.UpdateRole(actorUUID, userUUID, role)
+}
+
+// SetDisabled disables or re-enables an account. Disabling revokes every live session at once.
+func (s *UserAdminService) SetDisabled(actorUUID, userUUID string, disabled bool) (*models.User, error) {
+	user, err := s.GetUser(userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if user.UUID == actorUUID {
+		return nil, ErrSelfManagement
+	}
+
+	if err := s.userRepo.UpdateFields(user.ID, map[string]interface{}{"is_disabled": disabled}); err != nil {
+		return nil, fmt.Errorf("failed to update account status: %w", err)
+	}
+	user.IsDisabled = disabled
+
+	if disabled {
+		if err := s.tokenService.RevokeAll(context.Background(), user.ID); err != nil {
+			return nil, fmt.Errorf("account disabled but failed to revoke sessions: %w", err)
+		}
+	}
+	return user, nil
+}
+
+// ForcePasswordReset replaces the user's password with a temporary one and ends their sessions.
+// The user has to choose a new password before the API accepts their requests again.
+func (s *UserAdminService) ForcePasswordReset(userUUID string) (string, error) {
+	user, err := s.GetUser(userUUID)
+	if err != nil {
+		return "", err
+	}
+
+	temporaryPassword, err := utils.GenerateSecret(12)
+	if err != nil {
+		return "", err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(temporaryPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.userRepo.UpdateFields(user.ID, map[string]interface{}{
+		"password":                string(hashedPassword),
+		"password_reset_required": true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	if err := s.tokenService.RevokeAll(context.Background(), user.ID); err != nil {
+		return "", fmt.Errorf("password reset but failed to revoke sessions: %w", err)
+	}
+	return temporaryPassword, nil
+}
+
+// UnlockUser lifts a login lockout on a user's username; actorUUID is the admin doing it
+func (s *UserAdminService) UnlockUser(actorUUID, userUUID string) (*models.User, error) {
+	user, err := s.GetUser(userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loginGuard.UnlockUser(context.Background(), user.Username, actorUUID); err != nil {
+		return nil, fmt.Errorf("failed to unlock user: %w", err)
+	}
+	return user, nil
+}
+
+// UnlockIP lifts a login lockout on a client IP; actorUUID is the admin doing it
+func (s *UserAdminService) UnlockIP(actorUUID, ip string) error {
+	if err := s.loginGuard.UnlockIP(context.Background(), ip, actorUUID); err != nil {
+		return fmt.Errorf("failed to unlock IP %s: %w", ip, err)
+	}
+	return nil
+}
@@ -4,26 +4,58 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
+	"os"
 	"quiz-api/config"
+	"quiz-api/dto"
 	"quiz-api/models"
 	"quiz-api/repositories"
-	"quiz-api/utils"
-	"time"
+	"strings"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrAccountDisabled    = errors.New("account is disabled")
+	ErrEmailNotVerified   = errors.New("email address is not verified")
+	ErrEmailAlreadyExists = errors.New("email already exists")
+)
+
 type UserService struct {
-	UserRepo *repositories.UserRepository
-	Client   *config.RedisClient
+	UserRepo          *repositories.UserRepository
+	Client            *config.RedisClient
+	TokenService      *TokenService
+	SessionService    *SessionService
+	LoginGuard        *LoginGuardService
+	TwoFactor         *TwoFactorService
+	EmailTokenService *EmailTokenService
+	Mailer            Mailer
+	Logger            *logrus.Logger
 }
 
-func NewUserService(repo *repositories.UserRepository, client *config.RedisClient) *UserService {
-	return &UserService{UserRepo: repo, Client: client}
+func NewUserService(repo *repositories.UserRepository, client *config.RedisClient, tokenService *TokenService, sessionService *SessionService, loginGuard *LoginGuardService, twoFactor *TwoFactorService, emailTokenService *EmailTokenService, mailer Mailer, logger *logrus.Logger) *UserService {
+	return &UserService{
+		UserRepo:          repo,
+		Client:            client,
+		TokenService:      tokenService,
+		SessionService:    sessionService,
+		LoginGuard:        loginGuard,
+		TwoFactor:         twoFactor,
+		EmailTokenService: emailTokenService,
+		Mailer:            mailer,
+		Logger:            logger,
+	}
 }
 
-// Register a new user
-func (s *UserService) Register(username, password, fullname string) (*models.User, error) {
+// normalizeEmail makes email addresses comparable
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Register a new user; the account can log in once its email address is verified
+func (s *UserService) Register(ctx context.Context, username, password, fullname, email string) (*models.User, error) {
+	email = normalizeEmail(email)
 
 	existingUser, err := s.UserRepo.FindByUsername(username)
 	if err != nil {
@@ -34,6 +66,14 @@ func (s *UserService) Register(username, password, fullname string) (*models.Use
 		return nil, fmt.Errorf("username already exists")
 	}
 
+	existingUser, err = s.UserRepo.FindByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if existingUser != nil {
+		return nil, ErrEmailAlreadyExists
+	}
+
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
@@ -41,36 +81,64 @@ func (s *UserService) Register(username, password, fullname string) (*models.Use
 
 	user := &models.User{
 		Username: username,
+		Email:    email,
 		Password: string(hashedPassword),
 		FullName: fullname,
 	}
 
-	err = s.UserRepo.Create(user)
-	return user, err
+	if err := s.UserRepo.Create(user); err != nil {
+		return nil, err
+	}
+
+	// A failed delivery must not fail the registration; the user can ask for a new link
+	if err := s.sendVerificationEmail(ctx, user, email); err != nil {
+		s.Logger.WithError(err).WithField("user_uuid", user.UUID).Error("Failed to send verification email")
+	}
+	return user, nil
 }
 
-// Login a user and return JWT token
-func (s *UserService) Login(username, password string) (*models.User, string, error) {
+// Login a user and return an access and refresh token pair. When the account has 2FA
+// enabled a *TwoFactorChallengeError is returned instead, to be redeemed with a code.
+func (s *UserService) Login(username, password, userAgent, ip string) (*models.User, *dto.TokenResponse, error) {
+	ctx := context.Background()
+	if err := s.LoginGuard.Check(ctx, username, ip); err != nil {
+		s.LoginGuard.RecordBlocked(username, ip, err)
+		return nil, nil, err
+	}
+
 	user, err := s.UserRepo.FindByUsername(username)
 	if err != nil || user == nil {
-		return nil, "", errors.New("user not found")
+		s.LoginGuard.RecordFailure(ctx, username, ip, "user not found")
+		return nil, nil, errors.New("user not found")
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
 	if err != nil {
-		return nil, "", errors.New("invalid credentials")
+		s.LoginGuard.RecordFailure(ctx, username, ip, "invalid credentials")
+		return nil, nil, errors.New("invalid credentials")
 	}
+	s.LoginGuard.RecordSuccess(ctx, username)
 
-	// Generate JWT token
-	sessionUUID, token, err := utils.GenerateToken(user)
+	if user.IsDisabled {
+		return nil, nil, ErrAccountDisabled
+	}
+	// Accounts created before email addresses existed have none and are not held back
+	if user.Email != "" && !user.EmailVerified && os.Getenv("EMAIL_VERIFICATION_REQUIRED") != "false" {
+		return nil, nil, ErrEmailNotVerified
+	}
 
+	tokens, err := s.TwoFactor.StartLogin(ctx, user, userAgent, ip)
 	if err != nil {
-		return nil, "", err
+		return nil, nil, err
 	}
 
-	// Store session UUID in Redis
-	s.Client.Set(context.Background(), "user:"+fmt.Sprint(user.ID), sessionUUID, 24*time.Hour)
-	return user, token, nil
+	return user, tokens, nil
+}
+
+// RefreshToken rotates a refresh token and returns a new token pair
+func (s *UserService) RefreshToken(refreshToken string) (*dto.TokenResponse, error) {
+	_, tokens, err := s.TokenService.Refresh(context.Background(), refreshToken)
+	return tokens, err
 }
 
 // Change password for a user
@@ -90,11 +158,184 @@ func (s *UserService) ChangePassword(userId uint, oldPassword, newPassword strin
 		return err
 	}
 
-	return s.UserRepo.UpdatePassword(user.ID, string(hashedPassword))
+	if err := s.UserRepo.UpdatePassword(user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+	if user.PasswordResetRequired {
+		return s.UserRepo.UpdateFields(user.ID, map[string]interface{}{"password_reset_required": false})
+	}
+	return nil
+}
+
+// Logout a user and revoke every refresh token issued for the session
+func (s *UserService) Logout(userID uint, sessionUUID string) error {
+	return s.TokenService.RevokeFamily(context.Background(), userID, sessionUUID)
+}
+
+// ListSessions returns the active sessions of a user
+func (s *UserService) ListSessions(userID uint) ([]*models.Session, error) {
+	return s.SessionService.List(context.Background(), userID)
+}
+
+// RevokeSession ends one session of a user
+func (s *UserService) RevokeSession(userID uint, sessionUUID string) error {
+	ctx := context.Background()
+	if _, err := s.SessionService.Get(ctx, userID, sessionUUID); err != nil {
+		return err
+	}
+	return s.TokenService.RevokeFamily(ctx, userID, sessionUUID)
+}
+
+// RevokeAllSessions ends every session of a user
+func (s *UserService) RevokeAllSessions(userID uint) error {
+	return s.TokenService.RevokeAll(context.Background(), userID)
+}
+
+// ResendVerification sends a new verification link. Unknown or verified addresses are
+// ignored silently so the endpoint does not reveal which addresses are registered.
+func (s *UserService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.UserRepo.FindByEmail(normalizeEmail(email))
+	if err != nil {
+		return err
+	}
+	if user == nil || user.EmailVerified {
+		return nil
+	}
+	return s.sendVerificationEmail(ctx, user, user.Email)
+}
+
+// VerifyEmail redeems a verification link. A link sent after an email change also switches the address.
+func (s *UserService) VerifyEmail(token string) (*models.User, error) {
+	claims, err := s.EmailTokenService.Consume(context.Background(), EmailTokenVerification, token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.UserRepo.FindByUUID(claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidEmailToken
+	}
+
+	if claims.Email != user.Email {
+		existingUser, err := s.UserRepo.FindByEmail(claims.Email)
+		if err != nil {
+			return nil, err
+		}
+		if existingUser != nil {
+			return nil, ErrEmailAlreadyExists
+		}
+	}
+
+	if err := s.UserRepo.UpdateFields(user.ID, map[string]interface{}{"email": claims.Email, "email_verified": true}); err != nil {
+		return nil, err
+	}
+	user.Email = claims.Email
+	user.EmailVerified = true
+	return user, nil
+}
+
+// ChangeEmail sends a verification link to the new address; the address changes once it is verified
+func (s *UserService) ChangeEmail(ctx context.Context, userID uint, email string) error {
+	email = normalizeEmail(email)
+
+	user, err := s.UserRepo.FindById(userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	existingUser, err := s.UserRepo.FindByEmail(email)
+	if err != nil {
+		return err
+	}
+	if existingUser != nil && existingUser.ID != user.ID {
+		return ErrEmailAlreadyExists
+	}
+	return s.sendVerificationEmail(ctx, user, email)
+}
+
+// RequestPasswordReset emails a reset link to a verified address. Like ResendVerification
+// it never reveals whether the address belongs to an account.
+func (s *UserService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.UserRepo.FindByEmail(normalizeEmail(email))
+	if err != nil {
+		return err
+	}
+	if user == nil || !user.EmailVerified || user.IsDisabled {
+		return nil
+	}
+
+	token, err := s.EmailTokenService.Issue(ctx, EmailTokenPasswordReset, user, user.Email)
+	if err != nil {
+		return err
+	}
+	return s.sendMail(ctx, MailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your account %s.\n"+
+			"Open this link to choose a new password:\n\n%s\n\nIf it was not you, you can ignore this email.\n",
+			user.FullName, user.Username, appLink("/reset-password", token)),
+	})
+}
+
+// ResetPassword redeems a reset link, sets the new password and ends every session of the user
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	claims, err := s.EmailTokenService.Consume(ctx, EmailTokenPasswordReset, token)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.UserRepo.FindByUUID(claims.Subject)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.Email != claims.Email {
+		return ErrInvalidEmailToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.UserRepo.UpdateFields(user.ID, map[string]interface{}{
+		"password":                string(hashedPassword),
+		"password_reset_required": false,
+	}); err != nil {
+		return err
+	}
+
+	s.LoginGuard.UnlockUser(ctx, user.Username, user.UUID)
+	return s.TokenService.RevokeAll(ctx, user.ID)
+}
+
+func (s *UserService) sendVerificationEmail(ctx context.Context, user *models.User, email string) error {
+	token, err := s.EmailTokenService.Issue(ctx, EmailTokenVerification, user, email)
+	if err != nil {
+		return err
+	}
+	return s.sendMail(ctx, MailMessage{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that %s is your email address by opening this link:\n\n%s\n",
+			user.FullName, email, appLink("/verify-email", token)),
+	})
+}
+
+// sendMail hands a message to the mailer, giving up after mailSendTimeout so a stalled
+// mail server cannot hold the request
+func (s *UserService) sendMail(ctx context.Context, message MailMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+	defer cancel()
+	return s.Mailer.Send(ctx, message)
 }
 
-// Logout a user
-func (s *UserService) Logout(userID uint) error {
-	err := s.Client.Delete(context.Background(), "user:"+fmt.Sprint(userID))
-	return err
+// appLink builds a link to the web app (APP_URL) carrying a token
+func appLink(path, token string) string {
+	base := os.Getenv("APP_URL")
+	if base == "" {
+		base = "http://127.0.0.1:3000"
+	}
+	return strings.TrimSuffix(base, "/") + path + "?token=" + url.QueryEscape(token)
 }
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of the RFC 6238 test vectors, "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string // Last six digits of the RFC 6238 eight-digit codes
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != tt.want {
+			t.Errorf("TOTPCode at %d = %s, want %s", tt.unix, code, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := TOTPStep(now)
+	codeAt := func(step int64) string {
+		code, err := TOTPCode(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		skew     int
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", codeAt(current), 1, current, true},
+		{"previous step within skew", codeAt(current - 1), 1, current - 1, true},
+		{"next step within skew", codeAt(current + 1), 1, current + 1, true},
+		{"two steps behind", codeAt(current - 2), 1, 0, false},
+		{"two steps ahead", codeAt(current + 2), 1, 0, false},
+		{"previous step without skew", codeAt(current - 1), 0, 0, false},
+		{"surrounding spaces", " " + codeAt(current) + " ", 0, current, true},
+		{"too short", codeAt(current)[:TOTPDigits-1], 1, 0, false},
+		{"too long", codeAt(current) + "0", 1, 0, false},
+		{"empty", "", 1, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("ValidateTOTP = (%d, %v), want (%d, %v)", step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPInvalidSecret(t *testing.T) {
+	if _, ok := ValidateTOTP("not base32!", "123456", time.Now(), 1); ok {
+		t.Error("ValidateTOTP accepted a code for an invalid secret")
+	}
+}
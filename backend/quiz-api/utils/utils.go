@@ -1,34 +1,120 @@
 package utils
 
 import (
-	"bytes"
-	"encoding/json"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"os"
 	"quiz-api/models"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/google/uuid"
 )
 
-func GenerateToken(user *models.User) (string, string, error) {
-	sessionUUID := uuid.New()
-	claims := jwt.MapClaims{
+// TokenClaims returns the access token claims of a user session; the token is signed by the signing key service
+func TokenClaims(user *models.User, sessionUUID string) jwt.MapClaims {
+	return jwt.MapClaims{
 		"user_id":      user.ID,
 		"user_uuid":    user.UUID,
 		"session_uuid": sessionUUID,
 		"username":     user.Username,
 		"fullname":     user.FullName,
 		"is_admin":     user.IsAdmin,
-		"exp":          time.Now().Add(time.Hour * 24).Unix(),
+		"role":         user.Role,
+		"pwd_reset":    user.PasswordResetRequired,
+		"mfa_setup":    TwoFactorSetupRequired(user),
+		"exp":          time.Now().Add(AccessTokenTTL()).Unix(),
 	}
+}
+
+// GenerateRefreshToken returns a random opaque token suitable for use as a refresh token
+func GenerateRefreshToken() (string, error) {
+	return GenerateSecret(32)
+}
+
+// GenerateSecret returns size random bytes encoded as URL-safe base64
+func GenerateSecret(size int) (string, error) {
+	buf := make([]byte, size)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the hex encoded SHA-256 digest of a token, so raw tokens are never stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// AccessTokenTTL returns the lifetime of access tokens (ACCESS_TOKEN_TTL, default 15m)
+func AccessTokenTTL() time.Duration {
+	return DurationFromEnv("ACCESS_TOKEN_TTL", 15*time.Minute)
+}
+
+// GuestTokenTTL returns the lifetime of guest tokens (GUEST_TOKEN_TTL, default 2h)
+func GuestTokenTTL() time.Duration {
+	return DurationFromEnv("GUEST_TOKEN_TTL", 2*time.Hour)
+}
+
+// SignedTokenTTL returns the lifetime of the longest-lived tokens signed with the signing keys
+func SignedTokenTTL() time.Duration {
+	if guest := GuestTokenTTL(); guest > AccessTokenTTL() {
+		return guest
+	}
+	return AccessTokenTTL()
+}
+
+// TwoFactorRequired reports whether a user may not go without two-factor authentication (REQUIRE_ADMIN_2FA=true, admins only)
+func TwoFactorRequired(user *models.User) bool {
+	return user.IsAdmin && os.Getenv("REQUIRE_ADMIN_2FA") == "true"
+}
+
+// TwoFactorSetupRequired reports whether a user must enrol in two-factor authentication
+// before the session can be used for anything else
+func TwoFactorSetupRequired(user *models.User) bool {
+	return TwoFactorRequired(user) && !user.TOTPEnabled
+}
+
+// RefreshTokenTTL returns the lifetime of refresh tokens (REFRESH_TOKEN_TTL, default 7 days)
+func RefreshTokenTTL() time.Duration {
+	return DurationFromEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour)
+}
+
+// DurationFromEnv parses a duration such as "15m" from an environment variable, falling back when unset or invalid
+func DurationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+// TimeFromEnv parses an RFC 3339 time from an environment variable; unset or invalid gives the zero time
+func TimeFromEnv(key string) time.Time {
+	value, err := time.Parse(time.RFC3339, os.Getenv(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return value
+}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	jwtToken, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
-	return sessionUUID.String(), jwtToken, err
+// IntFromEnv parses a positive integer from an environment variable, falling back when unset or invalid
+func IntFromEnv(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
 }
 
 // SuccessResponse represents a standard success response
@@ -66,40 +152,3 @@ func SendError(c *gin.Context, statusCode int, message string) {
 		Message: message,
 	})
 }
-
-func SendNotification(socketId string, message string) error {
-	notifyURL := os.Getenv("NOTIFICATION_URL")
-	if notifyURL == "" {
-		return fmt.Errorf("NOTIFICATION_URL environment variable is not set")
-	}
-
-	notifyBody := map[string]string{
-		"socket_id": socketId,
-		"data":      message,
-	}
-
-	bodyBytes, err := json.Marshal(notifyBody)
-	if err != nil {
-		return fmt.Errorf("failed to marshal notification body: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", notifyURL, bytes.NewBuffer(bodyBytes))
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return fmt.Errorf("failed to send notification: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("notification failed with status: %s", resp.Status)
-	}
-
-	return nil
-}
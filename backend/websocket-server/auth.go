@@ -0,0 +1,210 @@
+// auth.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// tokenProtocolPrefix marks the subprotocol carrying the access token, for browsers that
+// cannot set headers on a websocket upgrade: new WebSocket(url, ["quiz", "bearer." + token])
+const tokenProtocolPrefix = "bearer."
+
+var (
+	ErrMissingToken    = errors.New("access token is required")
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrSessionNotFound = errors.New("session not found or revoked")
+)
+
+// Identity is the authenticated user behind a connection
+type Identity struct {
+	UserID      uint
+	UserUUID    string
+	FullName    string
+	SessionUUID string
+	GuestQuiz   string // Set for guest tokens, which may only join the room of that quiz
+	Role        string
+	IsAdmin     bool
+}
+
+func (i *Identity) IsGuest() bool {
+	return i.GuestQuiz != ""
+}
+
+// CanHost reports whether the user may run live sessions
+func (i *Identity) CanHost() bool {
+	return !i.IsGuest() && (i.IsAdmin || i.Role == "admin" || i.Role == "author")
+}
+
+// Authenticator checks the access tokens issued by quiz-api and the sessions
+// they belong to, the same way quiz-api's JWT middleware does
+type Authenticator struct {
+	jwks        *JWKSCache
+	redis       *redis.Client
+	secret      []byte
+	legacyUntil time.Time
+}
+
+func NewAuthenticator(cfg *Config, redisClient *redis.Client) *Authenticator {
+	return &Authenticator{
+		jwks:        NewJWKSCache(cfg.JWKSURL),
+		redis:       redisClient,
+		secret:      []byte(cfg.JWTSecret),
+		legacyUntil: cfg.JWTLegacyUntil,
+	}
+}
+
+// legacyAccepted reports whether kid-less HS256 tokens are still within their migration window
+func (a *Authenticator) legacyAccepted() bool {
+	return len(a.secret) > 0 && time.Now().Before(a.legacyUntil)
+}
+
+// tokenFromRequest reads the token from the token query parameter or the bearer subprotocol
+func tokenFromRequest(r *http.Request) string {
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	for _, protocol := range websocketProtocols(r) {
+		if strings.HasPrefix(protocol, tokenProtocolPrefix) {
+			return strings.TrimPrefix(protocol, tokenProtocolPrefix)
+		}
+	}
+	return ""
+}
+
+func websocketProtocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
+
+// Authenticate verifies a token and checks that its session or guest session is still active
+func (a *Authenticator) Authenticate(ctx context.Context, tokenString string) (*Identity, error) {
+	if tokenString == "" {
+		return nil, ErrMissingToken
+	}
+
+	methods := []string{"RS256", "EdDSA"}
+	legacy := a.legacyAccepted()
+	if legacy {
+		methods = append(methods, "HS256")
+	}
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			// Tokens without a kid predate key rotation and are signed with the shared secret
+			if !legacy || token.Method.Alg() != "HS256" {
+				return nil, ErrInvalidToken
+			}
+			return a.secret, nil
+		}
+		if token.Method.Alg() == "HS256" {
+			return nil, ErrInvalidToken
+		}
+		return a.jwks.Key(ctx, kid)
+	}, jwt.WithValidMethods(methods), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	identity, err := identityFromClaims(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.Validate(ctx, identity); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+func identityFromClaims(claims jwt.MapClaims) (*Identity, error) {
+	identity := &Identity{}
+	identity.UserUUID, _ = claims["user_uuid"].(string)
+	identity.FullName, _ = claims["fullname"].(string)
+	if identity.UserUUID == "" {
+		return nil, ErrInvalidToken
+	}
+
+	if isGuest, _ := claims["guest"].(bool); isGuest {
+		identity.GuestQuiz, _ = claims["quiz_uuid"].(string)
+		if identity.GuestQuiz == "" {
+			return nil, ErrInvalidToken
+		}
+		return identity, nil
+	}
+
+	userID, hasUserID := claims["user_id"].(float64)
+	identity.SessionUUID, _ = claims["session_uuid"].(string)
+	if !hasUserID || identity.SessionUUID == "" {
+		return nil, ErrInvalidToken
+	}
+	// A session still waiting for a forced password reset or 2FA enrolment may not play
+	if resetRequired, _ := claims["pwd_reset"].(bool); resetRequired {
+		return nil, ErrInvalidToken
+	}
+	if setupRequired, _ := claims["mfa_setup"].(bool); setupRequired {
+		return nil, ErrInvalidToken
+	}
+	identity.UserID = uint(userID)
+	identity.Role, _ = claims["role"].(string)
+	identity.IsAdmin, _ = claims["is_admin"].(bool)
+	return identity, nil
+}
+
+// Validate checks that the session behind a connection has not expired or been revoked.
+// The access token is only checked at upgrade; a connection lives as long as its session.
+func (a *Authenticator) Validate(ctx context.Context, identity *Identity) error {
+	if identity.IsGuest() {
+		var guest struct {
+			QuizUUID string `json:"quiz_uuid"`
+		}
+		if err := a.getJSON(ctx, "guest:"+identity.UserUUID, &guest); err != nil {
+			return err
+		}
+		if guest.QuizUUID != identity.GuestQuiz {
+			return ErrSessionNotFound
+		}
+		return nil
+	}
+
+	var session struct {
+		UserID uint `json:"user_id"`
+	}
+	if err := a.getJSON(ctx, "session:"+identity.SessionUUID, &session); err != nil {
+		return err
+	}
+	if session.UserID != identity.UserID {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (a *Authenticator) getJSON(ctx context.Context, key string, result interface{}) error {
+	data, err := a.redis.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check session in Redis: %w", err)
+	}
+	return json.Unmarshal(data, result)
+}
@@ -0,0 +1,138 @@
+// bridge.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl/plain"
+)
+
+// notificationEvent is what quiz-api publishes on the notifications topic
+type notificationEvent struct {
+	Type      string          `json:"type"`
+	UserUUID  string          `json:"user_uuid,omitempty"`
+	Room      string          `json:"room,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Timestamp int64           `json:"ts"`
+}
+
+// KafkaBridge delivers notification events to websocket clients. Instances share a
+// consumer group, so each event is read once and reaches the other instances
+// through the fanout.
+type KafkaBridge struct {
+	reader *kafka.Reader
+	server *Server
+}
+
+func NewKafkaBridge(cfg *Config, server *Server) *KafkaBridge {
+	return &KafkaBridge{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: cfg.KafkaBrokers,
+			GroupID: cfg.KafkaGroupID,
+			Topic:   cfg.NotificationTopic,
+			Dialer: &kafka.Dialer{
+				Timeout: 10 * time.Second,
+				SASLMechanism: plain.Mechanism{
+					Username: cfg.KafkaUsername,
+					Password: cfg.KafkaPassword,
+				},
+			},
+			CommitInterval: 0,
+			StartOffset:    kafka.LastOffset,
+			MaxWait:        100 * time.Millisecond,
+		}),
+		server: server,
+	}
+}
+
+// errMalformedEvent marks events that can never be delivered
+var errMalformedEvent = errors.New("malformed event")
+
+// Run consumes events until ctx is cancelled. An event is committed once the fanout
+// has published it; until then it is retried, and an event still unpublished at
+// shutdown is read again by the next consumer. Malformed events are logged and skipped.
+func (b *KafkaBridge) Run(ctx context.Context) {
+	for {
+		msg, err := b.reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+				return
+			}
+			log.Println("Bridge fetch error:", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for {
+			err := b.deliver(ctx, msg.Value)
+			if err == nil {
+				break
+			}
+			if errors.Is(err, errMalformedEvent) {
+				log.Printf("Bridge dropped event at offset %d: %v", msg.Offset, err)
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Bridge publish error at offset %d, retrying: %v", msg.Offset, err)
+			select {
+			case <-time.After(time.Second):
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err := b.reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Println("Bridge commit error:", err)
+		}
+	}
+}
+
+// deliver publishes an event to its room or user and waits for the fanout to acknowledge it
+func (b *KafkaBridge) deliver(ctx context.Context, value []byte) error {
+	var event notificationEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+	if event.Type == "" || (event.Room == "") == (event.UserUUID == "") {
+		return fmt.Errorf("%w: an event needs a type and either a room or a user_uuid", errMalformedEvent)
+	}
+
+	envelope := &Envelope{
+		Version:   ProtocolVersion,
+		Type:      event.Type,
+		Payload:   event.Payload,
+		Timestamp: event.Timestamp,
+	}
+	if envelope.Timestamp == 0 {
+		envelope.Timestamp = time.Now().UnixMilli()
+	}
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errMalformedEvent, err)
+	}
+
+	published := make(chan error, 1)
+	b.server.fanout.Publish(&Delivery{
+		Room:     event.Room,
+		UserUUID: event.UserUUID,
+		Data:     data,
+		ack:      func(err error) { published <- err },
+	})
+	select {
+	case err := <-published:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *KafkaBridge) Close() error {
+	return b.reader.Close()
+}
@@ -3,6 +3,8 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"time"
@@ -10,30 +12,64 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+var newline = []byte{'\n'}
+
 type Client struct {
-	server *Server
-	conn   *websocket.Conn
-	send   chan []byte
-	room   *Room
+	id       string // Addresses the connection across instances
+	server   *Server
+	conn     *websocket.Conn
+	queue    *OutboundQueue
+	codec    Codec
+	room     *Room
+	identity *Identity
+
+	// Owned by the hub: the client's position in its room's stream, and the join
+	// whose replay is being looked up along with the broadcasts held back meanwhile
+	stream  resumePoint
+	joining *roomRequest
+	held    []heldBroadcast
+
+	userUUID string
+	fullName string
 }
 
+// ServeWs authenticates the request and upgrades it; requests without a valid
+// token or an active session are refused before the upgrade
 func ServeWs(server *Server, w http.ResponseWriter, r *http.Request) {
-	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+	if server.draining.Load() {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "server shutting down", http.StatusServiceUnavailable)
+		return
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	identity, err := server.auth.Authenticate(ctx, tokenFromRequest(r))
+	cancel()
+	if err != nil {
+		status := http.StatusUnauthorized
+		if !errors.Is(err, ErrMissingToken) && !errors.Is(err, ErrInvalidToken) && !errors.Is(err, ErrSessionNotFound) {
+			log.Println("Authentication error:", err)
+			status = http.StatusInternalServerError
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	conn, err := server.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Upgrade error:", err)
 		return
 	}
 
 	client := &Client{
-		server: server,
-		conn:   conn,
-		send:   make(chan []byte, 256),
+		id:       randomHex(8),
+		server:   server,
+		conn:     conn,
+		queue:    NewOutboundQueue(server.sendQueueSize, server.policies),
+		codec:    codecFor(conn.Subprotocol()),
+		identity: identity,
+		userUUID: identity.UserUUID,
+		fullName: identity.FullName,
 	}
 	client.server.register <- client
 
@@ -46,7 +82,7 @@ func (c *Client) readPump() {
 		c.server.unregister <- c
 		c.conn.Close()
 	}()
-	c.conn.SetReadLimit(512)
+	c.conn.SetReadLimit(maxMessageSize)
 	c.conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.conn.SetPongHandler(func(string) error { c.conn.SetReadDeadline(time.Now().Add(60 * time.Second)); return nil })
 	for {
@@ -57,37 +93,52 @@ func (c *Client) readPump() {
 			}
 			break
 		}
-		message = bytes.TrimSpace(message)
-		c.server.broadcast <- message
+		c.server.dispatcher.Dispatch(c, message)
 	}
 }
 
+const (
+	maxMessageSize    = 4096
+	maxRoomNameLength = 64
+)
+
+// sendEnvelope queues a message for this client through the hub, which owns the send channel
+func (c *Client) sendEnvelope(msgType, requestID string, payload interface{}) {
+	data, err := EncodeEnvelope(msgType, requestID, payload)
+	if err != nil {
+		log.Printf("encode %s error: %v", msgType, err)
+		return
+	}
+	c.server.direct <- &Message{client: c, msgType: msgType, data: data}
+}
+
+// member identifies the client's user in a room's presence
+func (c *Client) member() Member {
+	return Member{UserUUID: c.userUUID, FullName: c.fullName}
+}
+
+func (c *Client) sendError(requestID string, err *ProtocolError) {
+	c.sendEnvelope(TypeError, requestID, err)
+}
+
 func (c *Client) writePump() {
 	ticker := time.NewTicker(54 * time.Second)
+	sessionTicker := time.NewTicker(c.server.sessionCheckInterval)
 	defer func() {
 		ticker.Stop()
+		sessionTicker.Stop()
 		c.conn.Close()
 	}()
 	for {
 		select {
-		case message, ok := <-c.send:
+		case <-c.queue.Ready():
+			frames, open := c.queue.Drain()
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			if err := c.writeFrames(frames); err != nil {
 				return
 			}
-			w, err := c.conn.NextWriter(websocket.TextMessage)
-			if err != nil {
-				return
-			}
-			w.Write(message)
-
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(<-c.send)
-			}
-
-			if err := w.Close(); err != nil {
+			if !open {
+				c.conn.WriteMessage(websocket.CloseMessage, c.queue.CloseData())
 				return
 			}
 		case <-ticker.C:
@@ -95,6 +146,54 @@ func (c *Client) writePump() {
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
+		case <-sessionTicker.C:
+			if !c.sessionActive() {
+				c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "session expired or revoked"))
+				return
+			}
+		}
+	}
+}
+
+// writeFrames sends everything queued as one frame in the client's codec. Frames at or
+// above the compression threshold are compressed when the client negotiated
+// permessage-deflate; smaller ones are not worth the CPU.
+func (c *Client) writeFrames(frames []*Frame) error {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for i, frame := range frames {
+		data, err := frame.Encode(c.codec)
+		if err != nil {
+			log.Println("Write error:", err)
+			continue
 		}
+		if i > 0 {
+			buf.Write(c.codec.Separator())
+		}
+		buf.Write(data)
+	}
+	if buf.Len() == 0 {
+		return nil
+	}
+
+	c.conn.EnableWriteCompression(buf.Len() >= c.server.compressionThreshold)
+	return c.conn.WriteMessage(c.codec.FrameType(), buf.Bytes())
+}
+
+// sessionActive reports whether the client's session still exists. Redis errors keep the
+// connection open; only a session known to be gone disconnects the client.
+func (c *Client) sessionActive() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := c.server.auth.Validate(ctx, c.identity)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		log.Println("Session check error:", err)
+		return true
 	}
+	return err == nil
 }
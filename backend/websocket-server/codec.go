@@ -0,0 +1,171 @@
+// codec.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// Subprotocols a client may offer at upgrade, in the server's order of preference.
+// Without one the connection speaks JSON.
+const (
+	SubprotocolMsgpack = "quiz.msgpack"
+	SubprotocolJSON    = "quiz.json"
+	SubprotocolLegacy  = "quiz" // JSON, kept for clients from before codec negotiation
+)
+
+// Codec is the wire format of a connection. Inside the server envelopes stay JSON, so
+// handlers, the fanout and replay buffers never see the codec; it only applies when
+// a frame is read or written.
+type Codec interface {
+	Name() string
+	FrameType() int
+	// DecodeEnvelope reads an incoming frame; the payload is kept as JSON
+	DecodeEnvelope(data []byte) (*Envelope, error)
+	// Encode converts an encoded JSON envelope to the wire format
+	Encode(envelope []byte) ([]byte, error)
+	// Separator goes between envelopes batched into one frame
+	Separator() []byte
+}
+
+func codecFor(subprotocol string) Codec {
+	if subprotocol == SubprotocolMsgpack {
+		return msgpackCodec{}
+	}
+	return jsonCodec{}
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string   { return "json" }
+func (jsonCodec) FrameType() int { return websocket.TextMessage }
+
+func (jsonCodec) DecodeEnvelope(data []byte) (*Envelope, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(bytes.TrimSpace(data), &envelope); err != nil {
+		return nil, err
+	}
+	return &envelope, nil
+}
+
+func (jsonCodec) Encode(envelope []byte) ([]byte, error) { return envelope, nil }
+func (jsonCodec) Separator() []byte                      { return newline }
+
+// msgpackCodec sends each envelope as a MessagePack map with the same keys as the
+// JSON one; batched envelopes are simply concatenated
+type msgpackCodec struct{}
+
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{}
+	h.RawToString = true
+	h.WriteExt = true
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}()
+
+func (msgpackCodec) Name() string   { return "msgpack" }
+func (msgpackCodec) FrameType() int { return websocket.BinaryMessage }
+
+func (msgpackCodec) DecodeEnvelope(data []byte) (*Envelope, error) {
+	var wire struct {
+		Version   int         `codec:"v"`
+		Type      string      `codec:"type"`
+		RequestID string      `codec:"request_id"`
+		Payload   interface{} `codec:"payload"`
+		Timestamp int64       `codec:"ts"`
+	}
+	if err := codec.NewDecoderBytes(data, msgpackHandle).Decode(&wire); err != nil {
+		return nil, err
+	}
+
+	envelope := &Envelope{Version: wire.Version, Type: wire.Type, RequestID: wire.RequestID, Timestamp: wire.Timestamp}
+	if wire.Payload != nil {
+		payload, err := json.Marshal(wire.Payload)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Payload = payload
+	}
+	return envelope, nil
+}
+
+func (msgpackCodec) Encode(envelope []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(envelope))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, msgpackHandle).Encode(fromJSONNumbers(value)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (msgpackCodec) Separator() []byte { return nil }
+
+// fromJSONNumbers turns json.Number into integers where possible so they are not sent as floats
+func fromJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = fromJSONNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fromJSONNumbers(item)
+		}
+	}
+	return value
+}
+
+// Frame is an outgoing envelope shared by every client it is queued for. The wire
+// form is computed once per codec, so a room broadcast is transcoded once however
+// many clients receive it.
+type Frame struct {
+	msgType string
+	data    []byte // Encoded JSON envelope
+
+	mu      sync.Mutex
+	encoded map[string][]byte
+}
+
+func NewFrame(msgType string, data []byte) *Frame {
+	return &Frame{msgType: msgType, data: data}
+}
+
+// Encode returns the frame in a codec's wire format
+func (f *Frame) Encode(c Codec) ([]byte, error) {
+	if _, ok := c.(jsonCodec); ok {
+		return f.data, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if data, ok := f.encoded[c.Name()]; ok {
+		return data, nil
+	}
+	data, err := c.Encode(f.data)
+	if err != nil {
+		return nil, fmt.Errorf("encode %s as %s: %w", f.msgType, c.Name(), err)
+	}
+	if f.encoded == nil {
+		f.encoded = make(map[string][]byte, 1)
+	}
+	f.encoded[c.Name()] = data
+	return data, nil
+}
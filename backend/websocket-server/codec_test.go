@@ -0,0 +1,155 @@
+// codec_test.go
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// countingConn counts the bytes that reach the client, after compression
+type countingConn struct {
+	net.Conn
+	read *int64
+}
+
+func (c countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddInt64(c.read, int64(n))
+	return n, err
+}
+
+// leaderboardFrames builds a batch like the one a large room receives every second
+func leaderboardFrames(players int) []*Frame {
+	type entry struct {
+		UserUUID string `json:"user_uuid"`
+		FullName string `json:"fullname"`
+		Score    int    `json:"score"`
+		Rank     int    `json:"rank"`
+	}
+	leaderboard := make([]entry, players)
+	for i := range leaderboard {
+		leaderboard[i] = entry{
+			UserUUID: fmt.Sprintf("6f1c2a4e-%04d-4b7e-9d3f-2a1b0c9d8e7f", i),
+			FullName: fmt.Sprintf("Player %d", i),
+			Score:    10000 - i*37,
+			Rank:     i + 1,
+		}
+	}
+	state, _ := EncodeEnvelope(sessionState, "", map[string]interface{}{"state": StateLeaderboard, "leaderboard": leaderboard})
+	progress, _ := EncodeEnvelope("session.progress", "", map[string]int{"answer_count": players})
+	return []*Frame{NewFrame(sessionState, state), NewFrame("session.progress", progress)}
+}
+
+// writePumpBefore is the batching writePump did before codecs: JSON text, one envelope per line
+func writePumpBefore(conn *websocket.Conn, frames []*Frame) error {
+	w, err := conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	for i, frame := range frames {
+		if i > 0 {
+			w.Write(newline)
+		}
+		w.Write(frame.data)
+	}
+	return w.Close()
+}
+
+func benchmarkWrite(b *testing.B, subprotocol string, compression, shared bool, write func(c *Client, frames []*Frame) error) {
+	server := &Server{compressionThreshold: 512}
+	upgrader := websocket.Upgrader{
+		Subprotocols:      []string{SubprotocolMsgpack, SubprotocolJSON},
+		EnableCompression: compression,
+	}
+	conns := make(chan *websocket.Conn, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			b.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	defer ts.Close()
+
+	var wireBytes int64
+	dialer := websocket.Dialer{
+		Subprotocols:      []string{subprotocol},
+		EnableCompression: compression,
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return countingConn{Conn: conn, read: &wireBytes}, nil
+		},
+	}
+	clientConn, _, err := dialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer clientConn.Close()
+	serverConn := <-conns
+	defer serverConn.Close()
+
+	received := make(chan struct{}, 1024)
+	go func() {
+		for {
+			if _, _, err := clientConn.ReadMessage(); err != nil {
+				return
+			}
+			received <- struct{}{}
+		}
+	}()
+
+	client := &Client{server: server, conn: serverConn, codec: codecFor(serverConn.Subprotocol())}
+	template := leaderboardFrames(200)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	start := atomic.LoadInt64(&wireBytes)
+	for i := 0; i < b.N; i++ {
+		// Fresh frames pay for their own transcoding; shared ones are what every
+		// client after the first in a room gets
+		frames := template
+		if !shared {
+			frames = make([]*Frame, len(template))
+			for j, frame := range template {
+				frames[j] = NewFrame(frame.msgType, frame.data)
+			}
+		}
+		if err := write(client, frames); err != nil {
+			b.Fatal(err)
+		}
+		select {
+		case <-received:
+		case <-time.After(5 * time.Second):
+			b.Fatal("frame not received")
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(atomic.LoadInt64(&wireBytes)-start)/float64(b.N), "wire-B/op")
+}
+
+// BenchmarkWriteLeaderboard compares the bytes on the wire and the cost of sending a
+// 200-player leaderboard batch with each codec, with and without permessage-deflate
+func BenchmarkWriteLeaderboard(b *testing.B) {
+	writeFrames := func(c *Client, frames []*Frame) error { return c.writeFrames(frames) }
+	before := func(c *Client, frames []*Frame) error { return writePumpBefore(c.conn, frames) }
+
+	b.Run("before-json", func(b *testing.B) { benchmarkWrite(b, SubprotocolJSON, false, false, before) })
+	b.Run("json", func(b *testing.B) { benchmarkWrite(b, SubprotocolJSON, false, false, writeFrames) })
+	b.Run("json-deflate", func(b *testing.B) { benchmarkWrite(b, SubprotocolJSON, true, false, writeFrames) })
+	b.Run("msgpack", func(b *testing.B) { benchmarkWrite(b, SubprotocolMsgpack, false, false, writeFrames) })
+	b.Run("msgpack-shared", func(b *testing.B) { benchmarkWrite(b, SubprotocolMsgpack, false, true, writeFrames) })
+	b.Run("msgpack-deflate", func(b *testing.B) { benchmarkWrite(b, SubprotocolMsgpack, true, false, writeFrames) })
+}
@@ -0,0 +1,133 @@
+// config.go
+package main
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the settings read from the environment
+type Config struct {
+	Addr                 string
+	AllowedOrigins       []string // "*" allows every origin; empty allows same-origin requests only
+	JWKSURL              string
+	JWTSecret            string    // Legacy HS256 secret for tokens issued before key rotation...
+	JWTLegacyUntil       time.Time // ...accepted until JWT_LEGACY_ACCEPT_UNTIL (RFC 3339); unset means never
+	RedisAddr            string
+	RedisPassword        string
+	RedisDB              int
+	Fanout               string // "memory" for a single instance, "redis" to share rooms across instances
+	SessionCheckInterval time.Duration
+	AnswerGrace          time.Duration // Latency allowance after a question's time limit
+	ReplaySize           int           // Broadcasts kept per room for clients that resume
+	ReplayTTL            time.Duration // How long an idle room's stream is kept before it restarts in a new epoch
+	SendQueueSize        int
+	Policies             map[string]Policy // Slow-consumer policy per message class
+	StatsAddr            string            // Serves per-client queue stats when set; keep it internal
+	DrainTimeout         time.Duration     // How long a shutdown waits for clients to disconnect
+	ReconnectJitter      time.Duration     // Upper bound of the reconnect delay suggested on shutdown
+	Compression          bool              // Offer permessage-deflate
+	CompressionThreshold int               // Smallest frame, in bytes, worth compressing
+	QuizStaticRoot       string            // quiz-api's static directory, or its /static URL
+	ScyllaHosts          []string
+	ScyllaKeyspace       string
+	ScyllaUsername       string
+	ScyllaPassword       string
+	KafkaBrokers         []string
+	KafkaUsername        string
+	KafkaPassword        string
+	KafkaGroupID         string
+	NotificationTopic    string
+}
+
+func LoadConfig() *Config {
+	return &Config{
+		Addr:                 envOrDefault("WS_ADDR", ":8080"),
+		AllowedOrigins:       splitList(os.Getenv("WS_ALLOWED_ORIGINS")),
+		JWKSURL:              os.Getenv("JWKS_URL"),
+		JWTSecret:            os.Getenv("JWT_SECRET"),
+		JWTLegacyUntil:       timeFromEnv("JWT_LEGACY_ACCEPT_UNTIL"),
+		RedisAddr:            os.Getenv("REDIS_ADDR"),
+		RedisPassword:        os.Getenv("REDIS_PASSWORD"),
+		RedisDB:              intFromEnv("REDIS_DB", 0),
+		Fanout:               envOrDefault("WS_FANOUT", "memory"),
+		SessionCheckInterval: durationFromEnv("WS_SESSION_CHECK_INTERVAL", time.Minute),
+		AnswerGrace:          durationFromEnv("WS_ANSWER_GRACE", 500*time.Millisecond),
+		ReplaySize:           intFromEnv("WS_REPLAY_BUFFER", 128),
+		ReplayTTL:            durationFromEnv("WS_REPLAY_TTL", 10*time.Minute),
+		SendQueueSize:        intFromEnv("WS_SEND_QUEUE", 256),
+		Policies:             policiesFromEnv("WS_SLOW_CONSUMER_POLICY"),
+		StatsAddr:            os.Getenv("WS_STATS_ADDR"),
+		DrainTimeout:         durationFromEnv("WS_DRAIN_TIMEOUT", 30*time.Second),
+		ReconnectJitter:      durationFromEnv("WS_RECONNECT_JITTER", 5*time.Second),
+		Compression:          os.Getenv("WS_COMPRESSION") != "false",
+		CompressionThreshold: intFromEnv("WS_COMPRESSION_THRESHOLD", 512),
+		QuizStaticRoot:       envOrDefault("QUIZ_STATIC_ROOT", "http://127.0.0.1:8080/static"),
+		ScyllaHosts:          splitList(os.Getenv("SCYLLADB_HOSTS")),
+		ScyllaKeyspace:       os.Getenv("SCYLLADB_KEYSPACE"),
+		ScyllaUsername:       os.Getenv("SCYLLADB_USERNAME"),
+		ScyllaPassword:       os.Getenv("SCYLLADB_PASSWORD"),
+		KafkaBrokers:         splitList(os.Getenv("KAFKA_BROKER")),
+		KafkaUsername:        os.Getenv("KAFKA_USERNAME"),
+		KafkaPassword:        os.Getenv("KAFKA_PASSWORD"),
+		KafkaGroupID:         envOrDefault("WS_KAFKA_GROUP_ID", "websocket-server"),
+		NotificationTopic:    envOrDefault("NOTIFICATION_TOPIC", "notifications"),
+	}
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// durationFromEnv parses a duration such as "15m", falling back when unset or invalid
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	duration, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || duration <= 0 {
+		return fallback
+	}
+	return duration
+}
+
+// timeFromEnv parses an RFC 3339 time, giving the zero time when unset or invalid
+func timeFromEnv(key string) time.Time {
+	value, err := time.Parse(time.RFC3339, os.Getenv(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return value
+}
+
+// intFromEnv parses a non-negative integer, falling back when unset or invalid
+func intFromEnv(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
+
+// policiesFromEnv parses class=policy pairs, falling back to the defaults when invalid
+func policiesFromEnv(key string) map[string]Policy {
+	policies, err := parsePolicies(os.Getenv(key))
+	if err != nil {
+		log.Printf("%s: %v, using the default policies", key, err)
+		policies, _ = parsePolicies("")
+	}
+	return policies
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
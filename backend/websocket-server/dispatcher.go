@@ -0,0 +1,59 @@
+// dispatcher.go
+package main
+
+import (
+	"log"
+)
+
+// HandlerFunc handles one message type. A non-nil result is sent back as a reply
+// correlated by request ID; an error is sent back as an error frame. Handlers that
+// answer later, once the hub has acted, return neither.
+type HandlerFunc func(c *Client, request *Envelope) (interface{}, error)
+
+// Dispatcher routes incoming envelopes to the handler registered for their type
+type Dispatcher struct {
+	handlers map[string]HandlerFunc
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[string]HandlerFunc)}
+}
+
+// Handle registers the handler of a message type
+func (d *Dispatcher) Handle(msgType string, handler HandlerFunc) {
+	d.handlers[msgType] = handler
+}
+
+// Dispatch decodes a frame and runs its handler. Malformed frames, other protocol
+// versions and unknown types are answered with an error frame and go no further.
+func (d *Dispatcher) Dispatch(c *Client, data []byte) {
+	request, err := c.codec.DecodeEnvelope(data)
+	if err != nil || request.Type == "" {
+		c.sendError("", NewProtocolError(ErrCodeBadRequest, "malformed message"))
+		return
+	}
+	if request.Version != ProtocolVersion {
+		c.sendError(request.RequestID, NewProtocolError(ErrCodeUnsupportedVersion, "unsupported protocol version"))
+		return
+	}
+
+	handler, ok := d.handlers[request.Type]
+	if !ok {
+		c.sendError(request.RequestID, NewProtocolError(ErrCodeUnknownType, "unknown message type "+request.Type))
+		return
+	}
+
+	result, err := handler(c, request)
+	if err != nil {
+		protocolErr, ok := err.(*ProtocolError)
+		if !ok {
+			log.Printf("handler %s error: %v", request.Type, err)
+			protocolErr = NewProtocolError(ErrCodeInternal, "internal error")
+		}
+		c.sendError(request.RequestID, protocolErr)
+		return
+	}
+	if result != nil {
+		c.sendEnvelope(TypeReply, request.RequestID, result)
+	}
+}
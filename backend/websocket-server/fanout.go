@@ -0,0 +1,298 @@
+// fanout.go
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Delivery is a message to hand to the local clients of a room or of a user, or to
+// one connection. Room deliveries carry their place in the room's stream, set when
+// published. A delivery carrying a Call is a live session command for this instance.
+type Delivery struct {
+	Room     string       `json:"room,omitempty"`
+	UserUUID string       `json:"user_uuid,omitempty"`
+	Conn     string       `json:"conn,omitempty"`
+	Epoch    string       `json:"epoch,omitempty"`
+	Seq      uint64       `json:"seq,omitempty"`
+	Data     []byte       `json:"data,omitempty"`
+	Call     *SessionCall `json:"call,omitempty"`
+
+	ack func(err error) // Called once the delivery is published, or failed to be, when set
+}
+
+// acknowledge reports the outcome of publishing the delivery to whoever waits for it
+func (d *Delivery) acknowledge(err error) {
+	if d.ack != nil {
+		d.ack(err)
+	}
+}
+
+// Fanout carries room broadcasts and direct messages to every server instance.
+// The hub tells it which rooms and users have local clients, publishes through it,
+// and delivers what arrives on Deliveries to its own clients, including what it
+// published itself. The fanout also numbers each room's broadcasts and keeps the
+// latest ones for clients that resume; Replay looks them up and answers on Replays.
+// And it counts each room's connections per user over every instance: AddMember,
+// RemoveMember and ListMembers answer on Presence.
+// A room's live session runs on the instance that claimed it: ForwardSession takes
+// commands there, and Send takes the replies back to the connection that sent them.
+// Methods are called from the hub and must not block on I/O, except ClaimSession.
+type Fanout interface {
+	JoinRoom(room string)
+	LeaveRoom(room string)
+	AddUser(userUUID string)
+	RemoveUser(userUUID string)
+	Publish(delivery *Delivery)
+	Deliveries() <-chan *Delivery
+	Replay(request *ReplayRequest)
+	Replays() <-chan *Replay
+	AddMember(room string, member Member)
+	RemoveMember(room string, member Member)
+	ListMembers(query *PresenceUpdate)
+	Presence() <-chan *PresenceUpdate
+	NodeID() string
+	// ClaimSession makes this instance the owner of a room's session unless another
+	// running instance owns it. It waits for the answer, so clients call it, not the hub.
+	ClaimSession(ctx context.Context, room string) (bool, error)
+	ReleaseSession(room string)
+	// ForwardSession delivers a session command to the owner of the room's session, or
+	// back to this instance when nobody owns it
+	ForwardSession(call *SessionCall)
+	Send(nodeID string, delivery *Delivery)
+	// Close stops the fanout, giving up on pending work when ctx ends
+	Close(ctx context.Context) error
+}
+
+// MemoryFanout delivers everything back to the local hub, for single-node deployments and tests
+type MemoryFanout struct {
+	queue      *queue[*Delivery]
+	deliveries chan *Delivery
+	replyQueue *queue[*Replay]
+	replays    chan *Replay
+	updates    *queue[*PresenceUpdate]
+	presence   chan *PresenceUpdate
+
+	mu         sync.Mutex // The Kafka bridge publishes from its own goroutine
+	streams    map[string]*ReplayBuffer
+	members    map[string]map[string]*Member // Room → user UUID → member
+	replaySize int
+	replayTTL  time.Duration
+	pruned     time.Time
+}
+
+func NewMemoryFanout(replaySize int, replayTTL time.Duration) *MemoryFanout {
+	f := &MemoryFanout{
+		queue:      newQueue[*Delivery](),
+		deliveries: make(chan *Delivery),
+		replyQueue: newQueue[*Replay](),
+		replays:    make(chan *Replay),
+		updates:    newQueue[*PresenceUpdate](),
+		presence:   make(chan *PresenceUpdate),
+		streams:    make(map[string]*ReplayBuffer),
+		members:    make(map[string]map[string]*Member),
+		replaySize: replaySize,
+		replayTTL:  replayTTL,
+		pruned:     time.Now(),
+	}
+	go forward(f.queue, f.deliveries)
+	go forward(f.replyQueue, f.replays)
+	go forward(f.updates, f.presence)
+	return f
+}
+
+func (f *MemoryFanout) JoinRoom(room string)       {}
+func (f *MemoryFanout) LeaveRoom(room string)      {}
+func (f *MemoryFanout) AddUser(userUUID string)    {}
+func (f *MemoryFanout) RemoveUser(userUUID string) {}
+
+func (f *MemoryFanout) Publish(delivery *Delivery) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// Numbered and queued under the lock, so deliveries leave in sequence order
+	if delivery.Room != "" {
+		f.stream(delivery.Room).Append(delivery)
+	}
+	f.queue.Push(delivery)
+	delivery.acknowledge(nil)
+}
+
+func (f *MemoryFanout) Deliveries() <-chan *Delivery {
+	return f.deliveries
+}
+
+func (f *MemoryFanout) Replay(request *ReplayRequest) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stream := f.stream(request.Room)
+	replay := &Replay{ReplayRequest: request, Epoch: stream.epoch, Seq: stream.seq}
+	if request.From != nil {
+		replay.Missed, replay.Complete = stream.Since(request.From.Epoch, request.From.Seq)
+	}
+	f.replyQueue.Push(replay)
+}
+
+func (f *MemoryFanout) Replays() <-chan *Replay {
+	return f.replays
+}
+
+func (f *MemoryFanout) AddMember(room string, member Member) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	members, ok := f.members[room]
+	if !ok {
+		members = make(map[string]*Member)
+		f.members[room] = members
+	}
+	present, ok := members[member.UserUUID]
+	if !ok {
+		present = &member
+		members[member.UserUUID] = present
+	}
+	present.Connections++
+	f.updates.Push(&PresenceUpdate{Room: room, Kind: presenceJoin, Member: &Member{UserUUID: present.UserUUID, FullName: present.FullName, Connections: present.Connections}, Count: len(members)})
+}
+
+func (f *MemoryFanout) RemoveMember(room string, member Member) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	members := f.members[room]
+	present, ok := members[member.UserUUID]
+	if !ok {
+		return
+	}
+	present.Connections--
+	if present.Connections == 0 {
+		delete(members, member.UserUUID)
+	}
+	if len(members) == 0 {
+		delete(f.members, room)
+	}
+	f.updates.Push(&PresenceUpdate{Room: room, Kind: presenceLeave, Member: &Member{UserUUID: present.UserUUID, FullName: present.FullName, Connections: present.Connections}, Count: len(members)})
+}
+
+func (f *MemoryFanout) ListMembers(query *PresenceUpdate) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, member := range f.members[query.Room] {
+		copied := *member
+		query.Members = append(query.Members, &copied)
+	}
+	query.Count = len(query.Members)
+	f.updates.Push(query)
+}
+
+func (f *MemoryFanout) Presence() <-chan *PresenceUpdate {
+	return f.presence
+}
+
+// Every session is owned by the only instance there is
+
+func (f *MemoryFanout) NodeID() string {
+	return "local"
+}
+
+func (f *MemoryFanout) ClaimSession(ctx context.Context, room string) (bool, error) {
+	return true, nil
+}
+
+func (f *MemoryFanout) ReleaseSession(room string) {}
+
+func (f *MemoryFanout) ForwardSession(call *SessionCall) {
+	f.queue.Push(&Delivery{Call: call})
+}
+
+func (f *MemoryFanout) Send(nodeID string, delivery *Delivery) {
+	f.queue.Push(delivery)
+}
+
+// stream returns a room's stream, starting a new epoch when the room sat idle for the
+// replay TTL. Idle streams are pruned at most once per TTL. Callers hold mu.
+func (f *MemoryFanout) stream(room string) *ReplayBuffer {
+	now := time.Now()
+	if now.Sub(f.pruned) > f.replayTTL {
+		for name, stream := range f.streams {
+			if now.Sub(stream.lastUsed) > f.replayTTL {
+				delete(f.streams, name)
+			}
+		}
+		f.pruned = now
+	}
+
+	stream, ok := f.streams[room]
+	if !ok || now.Sub(stream.lastUsed) > f.replayTTL {
+		stream = NewReplayBuffer(f.replaySize)
+		f.streams[room] = stream
+	}
+	stream.lastUsed = now
+	return stream
+}
+
+func (f *MemoryFanout) Close(ctx context.Context) error {
+	f.queue.Close()
+	f.replyQueue.Close()
+	f.updates.Close()
+	return nil
+}
+
+// forward hands queued items to a channel in order and closes it once the queue is closed and drained
+func forward[T any](q *queue[T], ch chan<- T) {
+	defer close(ch)
+	for {
+		item, ok := q.Pop()
+		if !ok {
+			return
+		}
+		ch <- item
+	}
+}
+
+// queue is an unbounded FIFO. Pushing never blocks, so the hub can hand work to a
+// goroutine that may itself be waiting on the hub without deadlocking.
+type queue[T any] struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	items  []T
+	closed bool
+}
+
+func newQueue[T any]() *queue[T] {
+	q := &queue[T]{}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *queue[T]) Push(item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.items = append(q.items, item)
+	q.cond.Signal()
+}
+
+// Pop waits for the next item; it returns false once the queue is closed and drained
+func (q *queue[T]) Pop() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.items) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	var item T
+	if len(q.items) == 0 {
+		return item, false
+	}
+	item = q.items[0]
+	q.items[0] = *new(T)
+	q.items = q.items[1:]
+	return item, true
+}
+
+func (q *queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
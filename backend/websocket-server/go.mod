@@ -2,4 +2,21 @@ module websocket-server
 
 go 1.23.2
 
-require github.com/gorilla/websocket v1.5.3
+require (
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/gocql/gocql v1.7.0
+	github.com/golang-jwt/jwt/v5 v5.2.1
+	github.com/gorilla/websocket v1.5.3
+	github.com/segmentio/kafka-go v0.4.47
+	github.com/ugorji/go/codec v1.2.12
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.1.2 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang/snappy v0.0.3 // indirect
+	github.com/hailocab/go-hostpool v0.0.0-20160125115350-e80d13ce29ed // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	gopkg.in/inf.v0 v0.9.1 // indirect
+)
@@ -0,0 +1,208 @@
+// handlers.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type roomPayload struct {
+	Room string `json:"room"`
+}
+
+type resumePayload struct {
+	Room string `json:"room"`
+	resumePoint
+}
+
+// sender identifies the author of a broadcast message
+type sender struct {
+	UserUUID string `json:"user_uuid"`
+	FullName string `json:"fullname"`
+}
+
+type roomMessagePayload struct {
+	From sender          `json:"from"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// registerHandlers wires the message types clients may send
+func registerHandlers(d *Dispatcher) {
+	d.Handle("ping", handlePing)
+	d.Handle("room.join", handleJoin)
+	d.Handle("room.resume", handleResume)
+	d.Handle("room.leave", handleLeave)
+	d.Handle("room.message", handleRoomMessage)
+	d.Handle("user.message", handleUserMessage)
+	d.Handle(presenceQuery, handlePresence)
+	d.Handle(sessionCreate, handleSessionCreate)
+	d.Handle(sessionStart, handleSessionControl)
+	d.Handle(sessionNext, handleSessionControl)
+	d.Handle(sessionSkip, handleSessionControl)
+	d.Handle(sessionState, handleSessionControl)
+	d.Handle(sessionAnswer, handleSessionAnswer)
+}
+
+func handlePing(c *Client, request *Envelope) (interface{}, error) {
+	return struct{}{}, nil
+}
+
+func handleJoin(c *Client, request *Envelope) (interface{}, error) {
+	var payload roomPayload
+	if err := request.Decode(&payload); err != nil {
+		return nil, NewProtocolError(ErrCodeBadRequest, "a room name of at most 64 characters is required")
+	}
+	if err := checkRoom(c, payload.Room); err != nil {
+		return nil, err
+	}
+
+	c.server.join <- &roomRequest{client: c, room: payload.Room, requestID: request.RequestID}
+	return nil, nil
+}
+
+// handleResume rejoins a room after a reconnect and replays the broadcasts missed since seq
+func handleResume(c *Client, request *Envelope) (interface{}, error) {
+	var payload resumePayload
+	if err := request.Decode(&payload); err != nil || payload.Epoch == "" {
+		return nil, NewProtocolError(ErrCodeBadRequest, "room, epoch and seq are required")
+	}
+	if err := checkRoom(c, payload.Room); err != nil {
+		return nil, err
+	}
+
+	c.server.join <- &roomRequest{client: c, room: payload.Room, requestID: request.RequestID, resume: &payload.resumePoint}
+	return nil, nil
+}
+
+func checkRoom(c *Client, room string) error {
+	if room == "" || len(room) > maxRoomNameLength {
+		return NewProtocolError(ErrCodeBadRequest, "a room name of at most 64 characters is required")
+	}
+	// Guests only ever play the quiz they joined with a code
+	if c.identity.IsGuest() && room != c.identity.GuestQuiz {
+		return NewProtocolError(ErrCodeForbidden, "guests may only join their own quiz")
+	}
+	return nil
+}
+
+func handleLeave(c *Client, request *Envelope) (interface{}, error) {
+	c.server.leave <- c
+	return struct{}{}, nil
+}
+
+// handleRoomMessage relays a payload to everyone in the sender's room; the hub refuses
+// it from players while a question of the room's session is open
+func handleRoomMessage(c *Client, request *Envelope) (interface{}, error) {
+	data, err := EncodeEnvelope("room.message", "", roomMessagePayload{
+		From: sender{UserUUID: c.userUUID, FullName: c.fullName},
+		Data: request.Payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.server.broadcast <- &Message{client: c, data: data, requestID: request.RequestID}
+	return nil, nil
+}
+
+// handlePresence lists who is in the sender's room; the hub sends the reply
+func handlePresence(c *Client, request *Envelope) (interface{}, error) {
+	c.server.presence <- &Message{client: c, requestID: request.RequestID}
+	return nil, nil
+}
+
+type userMessageRequest struct {
+	UserUUID string          `json:"user_uuid"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// handleUserMessage relays a payload to every connection of another user, whichever instance holds them
+func handleUserMessage(c *Client, request *Envelope) (interface{}, error) {
+	if c.identity.IsGuest() {
+		return nil, NewProtocolError(ErrCodeForbidden, "guests may not send direct messages")
+	}
+	var payload userMessageRequest
+	if err := request.Decode(&payload); err != nil || payload.UserUUID == "" {
+		return nil, NewProtocolError(ErrCodeBadRequest, "user_uuid is required")
+	}
+
+	data, err := EncodeEnvelope("user.message", "", roomMessagePayload{
+		From: sender{UserUUID: c.userUUID, FullName: c.fullName},
+		Data: payload.Data,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.server.publishUser(payload.UserUUID, data)
+	return struct{}{}, nil
+}
+
+type sessionCreatePayload struct {
+	QuizUUID string `json:"quiz_uuid"`
+}
+
+type sessionAnswerPayload struct {
+	QuestionUUID string   `json:"question_uuid"`
+	Answers      []string `json:"answers"`
+}
+
+// handleSessionCreate loads a published quiz and opens a live session in its room
+func handleSessionCreate(c *Client, request *Envelope) (interface{}, error) {
+	if !c.identity.CanHost() {
+		return nil, NewProtocolError(ErrCodeForbidden, "only admins and authors can host live sessions")
+	}
+	var payload sessionCreatePayload
+	if err := request.Decode(&payload); err != nil || payload.QuizUUID == "" {
+		return nil, NewProtocolError(ErrCodeBadRequest, "quiz_uuid is required")
+	}
+	if c.server.quizzes == nil {
+		return nil, NewProtocolError(ErrCodeInternal, "live sessions are not configured")
+	}
+
+	// Loading happens here rather than in the hub so slow storage never stalls it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	quiz, err := c.server.quizzes.LoadQuiz(ctx, payload.QuizUUID)
+	if errors.Is(err, ErrQuizNotPublished) {
+		return nil, NewProtocolError(ErrCodeBadRequest, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The session runs on this instance; players on the others reach it through the fanout
+	owned, err := c.server.fanout.ClaimSession(ctx, quiz.UUID)
+	if err != nil {
+		return nil, err
+	}
+	if !owned {
+		return nil, ErrSessionExists
+	}
+
+	c.server.sessions <- &sessionCommand{client: c, requestID: request.RequestID, kind: sessionCreate, quiz: quiz}
+	return nil, nil
+}
+
+// handleSessionControl forwards host commands and state queries to the hub
+func handleSessionControl(c *Client, request *Envelope) (interface{}, error) {
+	c.server.sessions <- &sessionCommand{client: c, requestID: request.RequestID, kind: request.Type}
+	return nil, nil
+}
+
+func handleSessionAnswer(c *Client, request *Envelope) (interface{}, error) {
+	receivedAt := time.Now()
+	var payload sessionAnswerPayload
+	if err := request.Decode(&payload); err != nil || payload.QuestionUUID == "" || len(payload.Answers) == 0 {
+		return nil, NewProtocolError(ErrCodeBadRequest, "question_uuid and answers are required")
+	}
+
+	c.server.sessions <- &sessionCommand{
+		client:     c,
+		requestID:  request.RequestID,
+		kind:       sessionAnswer,
+		question:   payload.QuestionUUID,
+		answers:    payload.Answers,
+		receivedAt: receivedAt,
+	}
+	return nil, nil
+}
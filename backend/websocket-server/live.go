@@ -0,0 +1,234 @@
+// live.go
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Live session commands, run by the hub on the room's session
+const (
+	sessionCreate = "session.create"
+	sessionStart  = "session.start"
+	sessionNext   = "session.next"
+	sessionSkip   = "session.skip"
+	sessionAnswer = "session.answer"
+	sessionState  = "session.state"
+)
+
+var (
+	ErrNoSession     = NewProtocolError("no_session", "there is no live session in this room")
+	ErrSessionExists = NewProtocolError("session_exists", "a live session is already running in this room")
+	ErrNotHost       = NewProtocolError(ErrCodeForbidden, "only the host can control the session")
+	ErrQuestionOpen  = NewProtocolError(ErrCodeForbidden, "only the host can message the room while a question is open")
+)
+
+// sessionCommand carries a live session request from a client to the hub
+type sessionCommand struct {
+	client     *Client
+	requestID  string
+	kind       string
+	quiz       *LiveQuiz // For session.create, loaded before reaching the hub
+	question   string
+	answers    []string
+	receivedAt time.Time
+}
+
+// sessionJoin tells the session's owner that a player joined the room on some instance
+const sessionJoin = "session.join"
+
+// SessionCall is a session command on its way to the instance running the room's
+// session, which may be another one. Node and Conn locate the connection the reply
+// goes to.
+type SessionCall struct {
+	Room       string    `json:"room"`
+	Kind       string    `json:"kind"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Node       string    `json:"node"`
+	Conn       string    `json:"conn"`
+	UserUUID   string    `json:"user_uuid"`
+	FullName   string    `json:"fullname"`
+	Question   string    `json:"question,omitempty"`
+	Answers    []string  `json:"answers,omitempty"`
+	ReceivedAt time.Time `json:"received_at,omitempty"`
+}
+
+// handleSessionCommand runs a command against the room's session when it runs here
+// and forwards it to the instance running it otherwise
+func (s *Server) handleSessionCommand(cmd *sessionCommand) {
+	if cmd.kind == sessionCreate {
+		s.createSession(cmd)
+		return
+	}
+
+	client := cmd.client
+	if _, ok := s.clients[client]; !ok {
+		return
+	}
+	room := client.room
+	if room == nil {
+		s.sendError(client, cmd.requestID, ErrNoSession)
+		return
+	}
+
+	call := s.sessionCall(client, cmd.kind)
+	call.RequestID = cmd.requestID
+	call.Question = cmd.question
+	call.Answers = cmd.answers
+	call.ReceivedAt = cmd.receivedAt
+	if room.session != nil {
+		s.runSessionCall(call)
+		return
+	}
+	s.fanout.ForwardSession(call)
+}
+
+func (s *Server) sessionCall(client *Client, kind string) *SessionCall {
+	return &SessionCall{
+		Room:     client.room.name,
+		Kind:     kind,
+		Node:     s.fanout.NodeID(),
+		Conn:     client.id,
+		UserUUID: client.userUUID,
+		FullName: client.fullName,
+	}
+}
+
+// runSessionCall runs a command on the instance owning the room's session and
+// broadcasts the new state to the room whenever it changes
+func (s *Server) runSessionCall(call *SessionCall) {
+	room, ok := s.rooms[call.Room]
+	if !ok || room.session == nil {
+		if call.Kind != sessionJoin {
+			s.replyCall(call, TypeError, ErrNoSession)
+		}
+		return
+	}
+	session := room.session
+
+	var err error
+	switch call.Kind {
+	case sessionJoin:
+		session.AddPlayer(call.UserUUID, call.FullName)
+		s.replyCall(call, sessionState, session.Snapshot())
+		return
+	case sessionState:
+		s.replyCall(call, TypeReply, session.Snapshot())
+		return
+	case sessionAnswer:
+		err = session.Answer(call.UserUUID, call.FullName, call.Question, call.Answers, call.ReceivedAt)
+		if err == nil {
+			s.replyCall(call, TypeReply, map[string]bool{"accepted": true})
+			s.broadcastEnvelope(room, "session.progress", map[string]int{"answer_count": len(session.currentRound().Answers)})
+			return
+		}
+	case sessionStart, sessionNext, sessionSkip:
+		if call.UserUUID != session.hostUUID {
+			s.replyCall(call, TypeError, ErrNotHost)
+			return
+		}
+		now := time.Now()
+		previous := session.currentRound()
+		switch call.Kind {
+		case sessionStart:
+			err = session.Start(now)
+		case sessionNext:
+			err = session.Next(now)
+		case sessionSkip:
+			err = session.Skip(now)
+		}
+		if err == nil && (session.currentRound() != previous || session.state != StateQuestion) {
+			stopTimer(previous)
+		}
+		s.scheduleTimeUp(room, session)
+	}
+	if err != nil {
+		s.replyCall(call, TypeError, err)
+		return
+	}
+
+	s.replyCall(call, TypeReply, struct{}{})
+	s.broadcastEnvelope(room, sessionState, session.Snapshot())
+	if session.state == StateFinished {
+		s.fanout.ReleaseSession(room.name)
+		if room.Empty() {
+			s.dropRoom(room)
+		}
+	}
+}
+
+// replyCall sends the reply to a session command to its connection, on whichever instance it is
+func (s *Server) replyCall(call *SessionCall, msgType string, payload interface{}) {
+	data, err := EncodeEnvelope(msgType, call.RequestID, payload)
+	if err != nil {
+		return
+	}
+	if call.Node != s.fanout.NodeID() {
+		s.fanout.Send(call.Node, &Delivery{Conn: call.Conn, Data: data})
+		return
+	}
+	if client, ok := s.conns[call.Conn]; ok {
+		s.sendTo(client, NewFrame(msgType, data))
+	}
+}
+
+// createSession moves the host into the quiz's room and opens its lobby. The client
+// claimed the session for this instance before the command reached the hub.
+func (s *Server) createSession(cmd *sessionCommand) {
+	client := cmd.client
+	if room, ok := s.rooms[cmd.quiz.UUID]; ok && room.session != nil && room.session.state != StateFinished {
+		s.sendError(client, cmd.requestID, ErrSessionExists)
+		return
+	}
+	if _, ok := s.clients[client]; !ok {
+		s.fanout.ReleaseSession(cmd.quiz.UUID)
+		return
+	}
+
+	s.joinRoom(client, cmd.quiz.UUID)
+	room := client.room
+	room.session = NewLiveSession(cmd.quiz, client.userUUID, s.answerGrace)
+	// Players already waiting in the room join the lobby, on this instance at once and
+	// on the others once the fanout lists them
+	for member := range room.clients {
+		room.session.AddPlayer(member.userUUID, member.fullName)
+	}
+	s.fanout.ListMembers(&PresenceUpdate{Room: room.name, Kind: presenceQuery, lobby: room.session})
+
+	s.sendEnvelope(client, TypeReply, cmd.requestID, room.session.Snapshot())
+	s.broadcastEnvelope(room, sessionState, room.session.Snapshot())
+}
+
+// fillLobby adds the players listed over every instance to a session's lobby
+func (s *Server) fillLobby(update *PresenceUpdate) {
+	room, ok := s.rooms[update.Room]
+	if !ok || room.session != update.lobby || room.session.state != StateLobby {
+		return
+	}
+	players := len(room.session.scores)
+	for _, member := range update.Members {
+		room.session.AddPlayer(member.UserUUID, member.FullName)
+	}
+	if len(room.session.scores) != players {
+		s.broadcastEnvelope(room, sessionState, room.session.Snapshot())
+	}
+}
+
+// observeSession keeps track of the state of a room's session from the session.state
+// messages reaching this instance, so rules depending on it apply wherever the session runs
+func observeSession(room *Room, data []byte) {
+	var envelope Envelope
+	var snapshot SessionSnapshot
+	if json.Unmarshal(data, &envelope) != nil || envelope.Decode(&snapshot) != nil || snapshot.QuizUUID != room.name {
+		return
+	}
+	room.live = &snapshot
+}
+
+func (s *Server) broadcastEnvelope(room *Room, msgType string, payload interface{}) {
+	data, err := EncodeEnvelope(msgType, "", payload)
+	if err != nil {
+		return
+	}
+	s.publishRoom(room.name, data)
+}
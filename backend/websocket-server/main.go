@@ -2,22 +2,128 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v8"
 )
 
 func main() {
-	server := NewServer()
+	cfg := LoadConfig()
+	if cfg.RedisAddr == "" {
+		log.Fatal("REDIS_ADDR is required")
+	}
+	if cfg.JWKSURL == "" {
+		log.Fatal("JWKS_URL is required")
+	}
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+		DB:       cfg.RedisDB,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := redisClient.Ping(ctx).Err(); err != nil {
+		log.Fatalf("failed to connect to Redis: %v", err)
+	}
+	cancel()
+
+	// Live sessions need the answer keys quiz-api exports to Scylla
+	var quizzes QuizSource
+	if len(cfg.ScyllaHosts) > 0 {
+		scylla, err := NewScyllaSession(cfg)
+		if err != nil {
+			log.Fatalf("failed to connect to ScyllaDB: %v", err)
+		}
+		defer scylla.Close()
+		quizzes = NewExportedQuizSource(cfg.QuizStaticRoot, scylla)
+	} else {
+		log.Println("SCYLLADB_HOSTS is not set, live sessions are disabled")
+	}
+
+	// Several instances behind a load balancer share room and user messages through Redis
+	var fanout Fanout
+	switch cfg.Fanout {
+	case "redis":
+		redisFanout, err := NewRedisFanout(redisClient, cfg.ReplaySize, cfg.ReplayTTL)
+		if err != nil {
+			log.Fatalf("failed to subscribe to Redis: %v", err)
+		}
+		fanout = redisFanout
+	case "memory":
+		fanout = NewMemoryFanout(cfg.ReplaySize, cfg.ReplayTTL)
+	default:
+		log.Fatalf("unknown WS_FANOUT %q, expected memory or redis", cfg.Fanout)
+	}
 
-	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+	server := NewServer(cfg, NewAuthenticator(cfg, redisClient), quizzes, fanout)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		ServeWs(server, w, r)
 	})
+	httpServer := &http.Server{Addr: cfg.Addr, Handler: mux}
 
 	go server.Run()
 
-	fmt.Println("Server started at :8080")
-	err := http.ListenAndServe(":8080", nil)
-	if err != nil {
-		fmt.Println("ListenAndServe: ", err)
+	if cfg.StatsAddr != "" {
+		stats := http.NewServeMux()
+		stats.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+			ServeStats(server, w, r)
+		})
+		go func() {
+			log.Println("Stats listening at", cfg.StatsAddr)
+			if err := http.ListenAndServe(cfg.StatsAddr, stats); err != nil {
+				log.Println("Stats server:", err)
+			}
+		}()
+	}
+
+	// Events from quiz-api, such as publish results, arrive through Kafka
+	bridgeCtx, stopBridge := context.WithCancel(context.Background())
+	defer stopBridge()
+	if len(cfg.KafkaBrokers) > 0 {
+		bridge := NewKafkaBridge(cfg, server)
+		defer bridge.Close()
+		go bridge.Run(bridgeCtx)
+	} else {
+		log.Println("KAFKA_BROKER is not set, notifications are disabled")
+	}
+
+	go func() {
+		fmt.Println("Server started at", cfg.Addr)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("ListenAndServe: %v", err)
+		}
+	}()
+
+	signals, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-signals.Done()
+	stop()
+
+	// Stop upgrades, tell clients to reconnect elsewhere and give them until the drain deadline
+	log.Printf("Shutting down, draining connections for up to %s", cfg.DrainTimeout)
+	ctx, cancel = context.WithTimeout(context.Background(), cfg.DrainTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(ctx); err != nil {
+		log.Println("HTTP shutdown:", err)
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		log.Println("Drain deadline reached, closed the remaining connections")
+	}
+	stopBridge()
+
+	// Leaving the fanout's Redis sets must not hold the exit up for long
+	closeCtx, cancelClose := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelClose()
+	if err := fanout.Close(closeCtx); err != nil {
+		log.Println("Fanout close:", err)
 	}
 }
@@ -0,0 +1,271 @@
+// outbound.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message classes decide what happens when a client's queue is full
+const (
+	ClassControl = "control" // Replies and errors
+	ClassEvent   = "event"   // Room messages, presence and other one-off events
+	ClassState   = "state"   // Latest-state messages; a newer one supersedes a queued one
+)
+
+// Slow-consumer policies
+type Policy string
+
+const (
+	PolicyDisconnect Policy = "disconnect"  // Never discarded; drop the client when no room can be made for it
+	PolicyDropOldest Policy = "drop_oldest" // Discard the oldest discardable message, else this one
+	PolicyCoalesce   Policy = "coalesce"    // Replace a queued message of the same type, else drop the oldest
+)
+
+// DefaultPolicies keeps replies reliable and sheds events and stale state first
+var DefaultPolicies = map[string]Policy{
+	ClassControl: PolicyDisconnect,
+	ClassEvent:   PolicyDropOldest,
+	ClassState:   PolicyCoalesce,
+}
+
+// classOf maps an envelope type to its message class
+func classOf(msgType string) string {
+	switch msgType {
+	case TypeReply, TypeError:
+		return ClassControl
+	case sessionState, "session.progress":
+		return ClassState
+	default:
+		return ClassEvent
+	}
+}
+
+// envelopeType reads the type of an encoded envelope
+func envelopeType(data []byte) string {
+	var header struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal(data, &header)
+	return header.Type
+}
+
+// parsePolicies reads "class=policy" pairs such as "event=drop_oldest,state=coalesce"
+// on top of the defaults
+func parsePolicies(value string) (map[string]Policy, error) {
+	policies := make(map[string]Policy, len(DefaultPolicies))
+	for class, policy := range DefaultPolicies {
+		policies[class] = policy
+	}
+	for _, item := range splitList(value) {
+		class, policy, ok := strings.Cut(item, "=")
+		if _, known := DefaultPolicies[class]; !ok || !known {
+			return nil, fmt.Errorf("unknown message class in %q", item)
+		}
+		switch Policy(policy) {
+		case PolicyDisconnect, PolicyDropOldest, PolicyCoalesce:
+			policies[class] = Policy(policy)
+		default:
+			return nil, fmt.Errorf("unknown slow-consumer policy in %q", item)
+		}
+	}
+	return policies, nil
+}
+
+type outbound struct {
+	frame  *Frame
+	policy Policy
+}
+
+// OutboundQueue holds a client's pending messages. The hub pushes without blocking,
+// the client's writePump drains it. A full queue makes room by discarding its oldest
+// discardable message, whatever is being pushed; only when none is left does the
+// policy of the message being pushed decide between discarding it and disconnecting.
+type OutboundQueue struct {
+	mu        sync.Mutex
+	items     []outbound
+	capacity  int
+	policies  map[string]Policy
+	closed    bool
+	closeData []byte // Payload of the close frame sent once the queue is drained
+	ready     chan struct{}
+	dropped   uint64
+	coalesced uint64
+}
+
+func NewOutboundQueue(capacity int, policies map[string]Policy) *OutboundQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &OutboundQueue{
+		capacity: capacity,
+		policies: policies,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+// Push queues a message and reports false when the client has to be disconnected
+func (q *OutboundQueue) Push(frame *Frame) bool {
+	return q.push(frame, q.policies[classOf(frame.msgType)])
+}
+
+// PushReliable queues a message that is never discarded, whatever its class
+func (q *OutboundQueue) PushReliable(frame *Frame) bool {
+	return q.push(frame, PolicyDisconnect)
+}
+
+func (q *OutboundQueue) push(frame *Frame, policy Policy) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return true
+	}
+
+	message := outbound{frame: frame, policy: policy}
+	if message.policy == PolicyCoalesce {
+		for i := len(q.items) - 1; i >= 0; i-- {
+			if q.items[i].policy == PolicyCoalesce && q.items[i].frame.msgType == frame.msgType {
+				q.items[i].frame = frame
+				q.coalesced++
+				return true
+			}
+		}
+	}
+
+	if len(q.items) >= q.capacity && !q.dropOldest() {
+		if message.policy == PolicyDisconnect {
+			return false
+		}
+		q.dropped++
+		return true
+	}
+	q.items = append(q.items, message)
+	q.signal()
+	return true
+}
+
+// dropOldest discards the oldest message that may be discarded
+func (q *OutboundQueue) dropOldest() bool {
+	for i, item := range q.items {
+		if item.policy != PolicyDisconnect {
+			q.items = append(q.items[:i], q.items[i+1:]...)
+			q.dropped++
+			return true
+		}
+	}
+	return false
+}
+
+func (q *OutboundQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Ready is signalled when messages are queued or the queue is closed
+func (q *OutboundQueue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+// Drain takes every queued message and reports whether the queue is still open
+func (q *OutboundQueue) Drain() ([]*Frame, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	messages := make([]*Frame, len(q.items))
+	for i, item := range q.items {
+		messages[i] = item.frame
+	}
+	q.items = q.items[:0]
+	return messages, !q.closed
+}
+
+// Close stops accepting messages; what is already queued is still drained
+func (q *OutboundQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.signal()
+}
+
+// CloseWith closes the queue and sets the close code the client receives after the last message
+func (q *OutboundQueue) CloseWith(code int, reason string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.closeData = websocket.FormatCloseMessage(code, reason)
+	q.signal()
+}
+
+// CloseData is the payload of the close frame, empty unless set by CloseWith
+func (q *OutboundQueue) CloseData() []byte {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closeData == nil {
+		return []byte{}
+	}
+	return q.closeData
+}
+
+// QueueStats is a snapshot of a client's outbound queue
+type QueueStats struct {
+	Depth     int    `json:"depth"`
+	Capacity  int    `json:"capacity"`
+	Dropped   uint64 `json:"dropped"`
+	Coalesced uint64 `json:"coalesced"`
+}
+
+func (q *OutboundQueue) Stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return QueueStats{Depth: len(q.items), Capacity: q.capacity, Dropped: q.dropped, Coalesced: q.coalesced}
+}
+
+// ClientStats describes one connection's outbound queue
+type ClientStats struct {
+	UserUUID string `json:"user_uuid"`
+	Room     string `json:"room,omitempty"`
+	QueueStats
+}
+
+func (s *Server) clientStats() []ClientStats {
+	stats := make([]ClientStats, 0, len(s.clients))
+	for client := range s.clients {
+		entry := ClientStats{UserUUID: client.userUUID, QueueStats: client.queue.Stats()}
+		if client.room != nil {
+			entry.Room = client.room.name
+		}
+		stats = append(stats, entry)
+	}
+	return stats
+}
+
+// ServeStats reports queue depth and drop counters for every connection
+func ServeStats(server *Server, w http.ResponseWriter, r *http.Request) {
+	reply := make(chan []ClientStats, 1)
+	server.stats <- reply
+	stats := <-reply
+
+	totals := QueueStats{}
+	for _, entry := range stats {
+		totals.Depth += entry.Depth
+		totals.Capacity += entry.Capacity
+		totals.Dropped += entry.Dropped
+		totals.Coalesced += entry.Coalesced
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"connections": len(stats),
+		"totals":      totals,
+		"clients":     stats,
+	})
+}
@@ -0,0 +1,106 @@
+// presence.go
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// Presence events broadcast to a room, and the query that lists its members
+const (
+	presenceJoin  = "presence.join"
+	presenceLeave = "presence.leave"
+	presenceQuery = "presence.list"
+)
+
+// Member is a user present in a room
+type Member struct {
+	UserUUID    string `json:"user_uuid"`
+	FullName    string `json:"fullname"`
+	Connections int    `json:"connections"`
+}
+
+type presenceEvent struct {
+	Room     string `json:"room"`
+	UserUUID string `json:"user_uuid"`
+	FullName string `json:"fullname"`
+	Count    int    `json:"count"` // Users present after the change
+}
+
+type presenceList struct {
+	Room    string    `json:"room"`
+	Count   int       `json:"count"`
+	Members []*Member `json:"members"`
+}
+
+// PresenceUpdate is the fanout's answer to a member joining or leaving a room, counted
+// over every instance, or to a presence.list query
+type PresenceUpdate struct {
+	Room    string
+	Kind    string       // presenceJoin, presenceLeave or presenceQuery
+	Member  *Member      // Who joined or left, with the connections they have left in the room
+	Count   int          // Users present after the change
+	Members []*Member    // Everyone present, for presenceQuery
+	query   *Message     // The query being answered; the fanout passes it through
+	lobby   *LiveSession // Or the session whose lobby the members join
+}
+
+// handlePresenceUpdate tells the room when a user's first connection arrives or their
+// last one leaves, on any instance, and answers presence queries. Disconnects, including
+// connections dropped by the ping/pong deadline, reach it through removeClient.
+func (s *Server) handlePresenceUpdate(update *PresenceUpdate) {
+	switch update.Kind {
+	case presenceJoin, presenceLeave:
+		first := update.Kind == presenceJoin && update.Member.Connections == 1
+		last := update.Kind == presenceLeave && update.Member.Connections == 0
+		if !first && !last {
+			return
+		}
+		data, err := EncodeEnvelope(update.Kind, "", presenceEvent{
+			Room:     update.Room,
+			UserUUID: update.Member.UserUUID,
+			FullName: update.Member.FullName,
+			Count:    update.Count,
+		})
+		if err != nil {
+			return
+		}
+		s.publishRoom(update.Room, data)
+	case presenceQuery:
+		if update.lobby != nil {
+			s.fillLobby(update)
+			return
+		}
+		s.sendPresenceList(update)
+	}
+}
+
+// listPresence asks the fanout for the members of the client's room
+func (s *Server) listPresence(message *Message) {
+	room := message.client.room
+	if room == nil {
+		s.sendError(message.client, message.requestID, NewProtocolError(ErrCodeBadRequest, "join a room first"))
+		return
+	}
+	s.fanout.ListMembers(&PresenceUpdate{Room: room.name, Kind: presenceQuery, query: message})
+}
+
+func (s *Server) sendPresenceList(update *PresenceUpdate) {
+	members := update.Members
+	if members == nil {
+		members = []*Member{}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		a, b := strings.ToLower(members[i].FullName), strings.ToLower(members[j].FullName)
+		if a != b {
+			return a < b
+		}
+		return members[i].UserUUID < members[j].UserUUID
+	})
+
+	s.sendEnvelope(update.query.client, TypeReply, update.query.requestID, presenceList{
+		Room:    update.Room,
+		Count:   len(members),
+		Members: members,
+	})
+}
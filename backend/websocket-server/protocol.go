@@ -0,0 +1,85 @@
+// protocol.go
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProtocolVersion is the envelope version this server speaks.
+// Frames carry one envelope each, as JSON text or, on the quiz.msgpack
+// subprotocol, as a MessagePack binary map. When the server batches several
+// envelopes into one frame, JSON ones are separated by newlines and
+// MessagePack ones follow each other.
+const ProtocolVersion = 1
+
+// Message types sent by the server
+const (
+	TypeReply = "reply"
+	TypeError = "error"
+)
+
+// Error codes carried by error frames
+const (
+	ErrCodeBadRequest         = "bad_request"
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeUnknownType        = "unknown_type"
+	ErrCodeForbidden          = "forbidden"
+	ErrCodeInternal           = "internal_error"
+)
+
+// Envelope wraps every message in both directions
+type Envelope struct {
+	Version   int             `json:"v"`
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id,omitempty"` // Set by clients; echoed on the reply or error
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Seq       uint64          `json:"seq,omitempty"` // Position in the room's stream, set on room broadcasts
+	Timestamp int64           `json:"ts"`            // Unix milliseconds, set by the sender
+}
+
+// Decode unmarshals the payload into a handler's request struct, whatever the client's codec
+func (e *Envelope) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// ProtocolError is returned by handlers and sent to the client as an error frame
+type ProtocolError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *ProtocolError) Error() string {
+	return e.Message
+}
+
+func NewProtocolError(code, message string) *ProtocolError {
+	return &ProtocolError{Code: code, Message: message}
+}
+
+// NewEnvelope builds an outgoing envelope with the current time
+func NewEnvelope(msgType, requestID string, payload interface{}) (*Envelope, error) {
+	envelope := &Envelope{
+		Version:   ProtocolVersion,
+		Type:      msgType,
+		RequestID: requestID,
+		Timestamp: time.Now().UnixMilli(),
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		envelope.Payload = data
+	}
+	return envelope, nil
+}
+
+// EncodeEnvelope builds and marshals an outgoing envelope
+func EncodeEnvelope(msgType, requestID string, payload interface{}) ([]byte, error) {
+	envelope, err := NewEnvelope(msgType, requestID, payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope)
+}
@@ -0,0 +1,195 @@
+// quiz.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+var ErrQuizNotPublished = errors.New("quiz is not published")
+
+// AnswerOption is an answer as shown to players
+type AnswerOption struct {
+	UUID        string `json:"uuid"`
+	Description string `json:"description"`
+}
+
+// LiveQuestion is a question of an exported quiz together with its answer key
+type LiveQuestion struct {
+	UUID           string         `json:"uuid"`
+	Description    string         `json:"description"`
+	Type           int            `json:"type"`
+	TimeLimit      int            `json:"time_limit"` // Seconds
+	Answers        []AnswerOption `json:"answers"`
+	CorrectAnswers string         `json:"-"` // Sorted, comma separated answer UUIDs
+	Score          int            `json:"-"`
+}
+
+// LiveQuiz is a published quiz in question order
+type LiveQuiz struct {
+	UUID      string
+	Title     string
+	Questions []*LiveQuestion
+}
+
+// QuizSource loads published quizzes for live sessions
+type QuizSource interface {
+	LoadQuiz(ctx context.Context, quizUUID string) (*LiveQuiz, error)
+}
+
+// ExportedQuizSource reads what quiz-api writes when a quiz is published: the static
+// JSON files for the question text, answers and time limits, and the Scylla
+// questions table for the answer key and scores
+type ExportedQuizSource struct {
+	staticRoot string // Local directory or http(s) URL of quiz-api's static files
+	scylla     *gocql.Session
+	httpClient *http.Client
+}
+
+func NewExportedQuizSource(staticRoot string, scylla *gocql.Session) *ExportedQuizSource {
+	return &ExportedQuizSource{
+		staticRoot: staticRoot,
+		scylla:     scylla,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// NewScyllaSession connects to the keyspace quiz-api exports quizzes to
+func NewScyllaSession(cfg *Config) (*gocql.Session, error) {
+	cluster := gocql.NewCluster(cfg.ScyllaHosts...)
+	cluster.Keyspace = cfg.ScyllaKeyspace
+	cluster.Consistency = gocql.Quorum
+	cluster.Authenticator = gocql.PasswordAuthenticator{
+		Username: cfg.ScyllaUsername,
+		Password: cfg.ScyllaPassword,
+	}
+	return cluster.CreateSession()
+}
+
+type exportedQuiz struct {
+	UUID         string `json:"uuid"`
+	Title        string `json:"title"`
+	QuestionUUID string `json:"question_uuid"`
+}
+
+type exportedQuestion struct {
+	LiveQuestion
+	NextQuestionUUID string `json:"next_question_uuid"`
+}
+
+func (s *ExportedQuizSource) LoadQuiz(ctx context.Context, quizUUID string) (*LiveQuiz, error) {
+	if _, err := gocql.ParseUUID(quizUUID); err != nil {
+		return nil, ErrQuizNotPublished
+	}
+
+	var header exportedQuiz
+	if err := s.readJSON(ctx, path.Join(quizUUID, "quiz.json"), &header); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrQuizNotPublished
+		}
+		return nil, err
+	}
+
+	answerKeys, err := s.loadAnswerKeys(ctx, quizUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	quiz := &LiveQuiz{UUID: header.UUID, Title: header.Title}
+	// Follow the next_question_uuid chain, which is already in position order
+	for next := header.QuestionUUID; next != ""; {
+		if len(quiz.Questions) > len(answerKeys) {
+			return nil, fmt.Errorf("question chain of quiz %s does not end", quizUUID)
+		}
+
+		var question exportedQuestion
+		if err := s.readJSON(ctx, path.Join(quizUUID, "questions", next+".json"), &question); err != nil {
+			return nil, err
+		}
+		key, ok := answerKeys[question.UUID]
+		if !ok {
+			return nil, fmt.Errorf("question %s has no answer key", question.UUID)
+		}
+		question.CorrectAnswers = key.answers
+		question.Score = key.score
+
+		quiz.Questions = append(quiz.Questions, &question.LiveQuestion)
+		next = question.NextQuestionUUID
+	}
+	if len(quiz.Questions) == 0 {
+		return nil, ErrQuizNotPublished
+	}
+	return quiz, nil
+}
+
+type answerKey struct {
+	answers string
+	score   int
+}
+
+func (s *ExportedQuizSource) loadAnswerKeys(ctx context.Context, quizUUID string) (map[string]answerKey, error) {
+	keys := make(map[string]answerKey)
+	iter := s.scylla.Query(`SELECT question_uuid, answers, score FROM questions WHERE quiz_uuid = ?`, quizUUID).WithContext(ctx).Iter()
+
+	var questionUUID gocql.UUID
+	var key answerKey
+	for iter.Scan(&questionUUID, &key.answers, &key.score) {
+		keys[questionUUID.String()] = key
+	}
+	if err := iter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to load answer keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *ExportedQuizSource) readJSON(ctx context.Context, name string, result interface{}) error {
+	var data []byte
+	var err error
+	if strings.HasPrefix(s.staticRoot, "http://") || strings.HasPrefix(s.staticRoot, "https://") {
+		data, err = s.fetch(ctx, strings.TrimSuffix(s.staticRoot, "/")+"/"+name)
+	} else {
+		data, err = os.ReadFile(filepath.Join(s.staticRoot, filepath.FromSlash(name)))
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+func (s *ExportedQuizSource) fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, os.ErrNotExist
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: status %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// normalizeAnswers turns submitted answer UUIDs into the form of the answer key
+func normalizeAnswers(answers []string) string {
+	sorted := append([]string(nil), answers...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
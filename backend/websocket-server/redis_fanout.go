@@ -0,0 +1,671 @@
+// redis_fanout.go
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RedisFanout connects server instances through Redis. Every instance subscribes to
+// its own ws:node:<id> channel, and the ws:room_nodes:<room> and ws:user_nodes:<uuid>
+// sets record which instances have clients in a room or for a user. Publishing looks
+// up those sets and publishes once per instance; an instance that no longer receives
+// on its channel is removed from the set.
+//
+// Each room's stream lives in Redis too, under ws:room_epoch:<room>, ws:room_seq:<room>
+// and the capped list ws:room_log:<room>, so every instance numbers the room's
+// broadcasts the same way and a client can resume on any of them.
+//
+// Presence is counted per instance, in the ws:presence:<room>:<node> hash of user UUID
+// to connections with full names in ws:presence_names:<room>:<node>, and the
+// ws:presence_nodes:<room> set lists the instances counting connections in the room.
+// Every instance keeps its ws:node_alive:<node> key from expiring while it runs; the
+// entries of an instance whose key expired, because it crashed or lost Redis for
+// longer than nodeTTL, are dropped when presence is read. An instance that finds its
+// own key expired writes its entries back.
+//
+// ws:session_owner:<room> names the instance running the room's live session. Session
+// commands are published on the owner's channel, and the owner publishes each reply on
+// the channel of the instance holding the connection. A session whose owner stopped
+// can be claimed again by any instance.
+type RedisFanout struct {
+	client     *redis.Client
+	nodeID     string
+	pubsub     *redis.PubSub
+	ops        *queue[func(ctx context.Context)]
+	deliveries chan *Delivery
+	replyQueue *queue[*Replay]
+	replays    chan *Replay
+	updates    *queue[*PresenceUpdate]
+	presence   chan *PresenceUpdate
+	done       chan struct{}
+	ctx        context.Context // Parent of every Redis operation; cancelled when Close runs out of time
+	cancel     context.CancelFunc
+	replaySize int
+	replayTTL  time.Duration
+
+	// Owned by the ops goroutine; used to leave every set on close and to restore them
+	// after this instance's key expired
+	rooms    map[string]bool
+	users    map[string]bool
+	members  map[string]map[string]*Member // Room → user UUID → member, with this instance's connections
+	sessions map[string]bool               // Rooms whose session this instance owns
+}
+
+// nodeTTL is how long an instance counts as alive after its last heartbeat
+const nodeTTL = 30 * time.Second
+
+func NewRedisFanout(client *redis.Client, replaySize int, replayTTL time.Duration) (*RedisFanout, error) {
+	if replaySize < 1 {
+		replaySize = 1
+	}
+	opsCtx, cancelOps := context.WithCancel(context.Background())
+	f := &RedisFanout{
+		ctx:        opsCtx,
+		cancel:     cancelOps,
+		client:     client,
+		nodeID:     randomHex(8),
+		ops:        newQueue[func(ctx context.Context)](),
+		deliveries: make(chan *Delivery),
+		replyQueue: newQueue[*Replay](),
+		replays:    make(chan *Replay),
+		updates:    newQueue[*PresenceUpdate](),
+		presence:   make(chan *PresenceUpdate),
+		done:       make(chan struct{}),
+		replaySize: replaySize,
+		replayTTL:  replayTTL,
+		rooms:      make(map[string]bool),
+		users:      make(map[string]bool),
+		members:    make(map[string]map[string]*Member),
+		sessions:   make(map[string]bool),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Set(ctx, nodeAliveKey(f.nodeID), 1, nodeTTL).Err(); err != nil {
+		cancelOps()
+		return nil, err
+	}
+	f.pubsub = client.Subscribe(ctx, f.nodeChannel(f.nodeID))
+	// Wait for the subscription so nothing published to this node is missed
+	if _, err := f.pubsub.Receive(ctx); err != nil {
+		f.pubsub.Close()
+		cancelOps()
+		return nil, err
+	}
+
+	go f.receive()
+	go f.process()
+	go f.heartbeat()
+	go forward(f.replyQueue, f.replays)
+	go forward(f.updates, f.presence)
+	return f, nil
+}
+
+func (f *RedisFanout) nodeChannel(nodeID string) string {
+	return "ws:node:" + nodeID
+}
+
+func roomNodesKey(room string) string {
+	return "ws:room_nodes:" + room
+}
+
+func userNodesKey(userUUID string) string {
+	return "ws:user_nodes:" + userUUID
+}
+
+func nodeAliveKey(nodeID string) string {
+	return "ws:node_alive:" + nodeID
+}
+
+func presenceNodesKey(room string) string {
+	return "ws:presence_nodes:" + room
+}
+
+// presenceKeys are the connection count and full name hashes of an instance's presence in a room
+func presenceKeys(room, nodeID string) []string {
+	return []string{"ws:presence:" + room + ":" + nodeID, "ws:presence_names:" + room + ":" + nodeID}
+}
+
+// presenceArgs are the arguments every presence script starts with: the prefixes of the
+// per-instance hashes and of the liveness keys, and this instance
+func (f *RedisFanout) presenceArgs(room string) []interface{} {
+	keys := presenceKeys(room, "")
+	return []interface{}{keys[0], keys[1], nodeAliveKey(""), f.nodeID}
+}
+
+// presenceScriptPrelude defines collect, which adds up the presence of the instances
+// still alive and drops the entries of the others.
+// KEYS: presence nodes. ARGV: connections prefix, names prefix, liveness prefix, this node.
+const presenceScriptPrelude = `
+local function collect()
+	local members = {}
+	for _, node in ipairs(redis.call('SMEMBERS', KEYS[1])) do
+		if node == ARGV[4] or redis.call('EXISTS', ARGV[3] .. node) == 1 then
+			local connections = redis.call('HGETALL', ARGV[1] .. node)
+			for i = 1, #connections, 2 do
+				local user = connections[i]
+				local member = members[user]
+				if not member then
+					member = {0, redis.call('HGET', ARGV[2] .. node, user) or ''}
+					members[user] = member
+				end
+				member[1] = member[1] + tonumber(connections[i + 1])
+			end
+		else
+			redis.call('SREM', KEYS[1], node)
+			redis.call('DEL', ARGV[1] .. node, ARGV[2] .. node)
+		end
+	end
+	return members
+end
+`
+
+// presenceScript adds delta connections for a user in a room on this instance and returns
+// the connections they have there over every instance and the number of users present.
+// ARGV after the prelude's: user UUID, full name, delta.
+var presenceScript = redis.NewScript(presenceScriptPrelude + `
+local connections_key, names_key = ARGV[1] .. ARGV[4], ARGV[2] .. ARGV[4]
+if redis.call('HINCRBY', connections_key, ARGV[5], ARGV[7]) > 0 then
+	redis.call('HSET', names_key, ARGV[5], ARGV[6])
+	redis.call('SADD', KEYS[1], ARGV[4])
+else
+	redis.call('HDEL', connections_key, ARGV[5])
+	redis.call('HDEL', names_key, ARGV[5])
+	if redis.call('HLEN', connections_key) == 0 then
+		redis.call('SREM', KEYS[1], ARGV[4])
+	end
+end
+local connections, count = 0, 0
+for user, member in pairs(collect()) do
+	count = count + 1
+	if user == ARGV[5] then
+		connections = member[1]
+	end
+end
+return {connections, count}
+`)
+
+// listPresenceScript returns user UUID, full name and connections of everyone in a room
+var listPresenceScript = redis.NewScript(presenceScriptPrelude + `
+local list = {}
+for user, member in pairs(collect()) do
+	table.insert(list, user)
+	table.insert(list, member[2])
+	table.insert(list, member[1])
+end
+return list
+`)
+
+func sessionOwnerKey(room string) string {
+	return "ws:session_owner:" + room
+}
+
+// claimSessionScript makes this instance the owner of a room's session unless a
+// running instance owns it. KEYS: owner. ARGV: this node, liveness prefix.
+var claimSessionScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner and owner ~= ARGV[1] and redis.call('EXISTS', ARGV[2] .. owner) == 1 then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1])
+return 1
+`)
+
+// releaseSessionScript gives up a session this instance owns. KEYS: owner. ARGV: this node.
+var releaseSessionScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// forwardSessionScript publishes a session command to the running instance that owns
+// the room's session, or to this one when there is none.
+// KEYS: owner. ARGV: this node, liveness prefix, node channel prefix, delivery JSON.
+var forwardSessionScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if owner and redis.call('EXISTS', ARGV[2] .. owner) == 1 and redis.call('PUBLISH', ARGV[3] .. owner, ARGV[4]) > 0 then
+	return 1
+end
+redis.call('PUBLISH', ARGV[3] .. ARGV[1], ARGV[4])
+return 0
+`)
+
+// roomStreamKeys are the epoch, sequence and log keys of a room's stream
+func roomStreamKeys(room string) []string {
+	return []string{"ws:room_epoch:" + room, "ws:room_seq:" + room, "ws:room_log:" + room}
+}
+
+// Both stream scripts start a new epoch, with an empty log, when the stream expired,
+// and keep it alive for the replay TTL.
+const streamScriptPrelude = `
+local epoch = redis.call('GET', KEYS[1])
+if not epoch then
+	epoch = ARGV[1]
+	redis.call('DEL', KEYS[2], KEYS[3])
+end
+redis.call('SET', KEYS[1], epoch, 'PX', ARGV[2])
+`
+
+// publishRoomScript numbers a room delivery, appends it to the room's log and publishes it
+// to every instance in the room in one step, so each instance receives the room's
+// broadcasts in sequence order. Instances nobody listens for are taken out of the room.
+// KEYS: epoch, seq, log, room nodes. ARGV: new epoch, TTL in ms, log size, delivery JSON
+// without epoch and seq, node channel prefix, this node.
+var publishRoomScript = redis.NewScript(streamScriptPrelude + `
+local seq = redis.call('INCR', KEYS[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+local entry = '{"epoch":"' .. epoch .. '","seq":' .. seq .. ',' .. string.sub(ARGV[4], 2)
+redis.call('RPUSH', KEYS[3], entry)
+redis.call('LTRIM', KEYS[3], -tonumber(ARGV[3]), -1)
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+for _, node in ipairs(redis.call('SMEMBERS', KEYS[4])) do
+	if redis.call('PUBLISH', ARGV[5] .. node, entry) == 0 and node ~= ARGV[6] then
+		redis.call('SREM', KEYS[4], node)
+	end
+end
+return seq
+`)
+
+// replayScript returns the stream's epoch and last sequence number, whether the
+// broadcasts after the resume point are all still logged, and those broadcasts.
+// KEYS: epoch, seq, log. ARGV: new epoch, TTL in ms, resume epoch, resume seq.
+var replayScript = redis.NewScript(streamScriptPrelude + `
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+redis.call('PEXPIRE', KEYS[3], ARGV[2])
+local seq = tonumber(redis.call('GET', KEYS[2]) or '0')
+if ARGV[3] ~= epoch then
+	return {epoch, seq, 0, {}}
+end
+local missed = seq - tonumber(ARGV[4])
+if missed < 0 or missed > redis.call('LLEN', KEYS[3]) then
+	return {epoch, seq, 0, {}}
+end
+if missed == 0 then
+	return {epoch, seq, 1, {}}
+end
+return {epoch, seq, 1, redis.call('LRANGE', KEYS[3], -missed, -1)}
+`)
+
+func (f *RedisFanout) JoinRoom(room string) {
+	f.ops.Push(func(ctx context.Context) {
+		f.rooms[room] = true
+		f.exec(f.client.SAdd(ctx, roomNodesKey(room), f.nodeID).Err())
+	})
+}
+
+func (f *RedisFanout) LeaveRoom(room string) {
+	f.ops.Push(func(ctx context.Context) {
+		delete(f.rooms, room)
+		f.exec(f.client.SRem(ctx, roomNodesKey(room), f.nodeID).Err())
+	})
+}
+
+func (f *RedisFanout) AddUser(userUUID string) {
+	f.ops.Push(func(ctx context.Context) {
+		f.users[userUUID] = true
+		f.exec(f.client.SAdd(ctx, userNodesKey(userUUID), f.nodeID).Err())
+	})
+}
+
+func (f *RedisFanout) RemoveUser(userUUID string) {
+	f.ops.Push(func(ctx context.Context) {
+		delete(f.users, userUUID)
+		f.exec(f.client.SRem(ctx, userNodesKey(userUUID), f.nodeID).Err())
+	})
+}
+
+func (f *RedisFanout) Publish(delivery *Delivery) {
+	f.ops.Push(func(ctx context.Context) {
+		if delivery.Room != "" {
+			delivery.acknowledge(f.publishRoom(ctx, delivery))
+			return
+		}
+		delivery.acknowledge(f.publishUser(ctx, delivery))
+	})
+}
+
+func (f *RedisFanout) publishUser(ctx context.Context, delivery *Delivery) error {
+	key := userNodesKey(delivery.UserUUID)
+	nodes, err := f.client.SMembers(ctx, key).Result()
+	if err != nil {
+		f.exec(err)
+		return err
+	}
+
+	payload, err := json.Marshal(delivery)
+	if err != nil {
+		f.exec(err)
+		return err
+	}
+	var failed error
+	for _, node := range nodes {
+		receivers, err := f.client.Publish(ctx, f.nodeChannel(node), payload).Result()
+		if err != nil {
+			f.exec(err)
+			failed = err
+			continue
+		}
+		// Nobody listens on the channel of an instance that stopped without leaving
+		if receivers == 0 && node != f.nodeID {
+			f.exec(f.client.SRem(ctx, key, node).Err())
+		}
+	}
+	return failed
+}
+
+func (f *RedisFanout) publishRoom(ctx context.Context, delivery *Delivery) error {
+	payload, err := json.Marshal(delivery)
+	if err == nil {
+		keys := append(roomStreamKeys(delivery.Room), roomNodesKey(delivery.Room))
+		err = publishRoomScript.Run(ctx, f.client, keys,
+			randomHex(8), f.replayTTL.Milliseconds(), f.replaySize, payload, f.nodeChannel(""), f.nodeID).Err()
+	}
+	f.exec(err)
+	return err
+}
+
+func (f *RedisFanout) Deliveries() <-chan *Delivery {
+	return f.deliveries
+}
+
+// Replay reads the room's stream after the join queued before it, so every broadcast
+// numbered after the read reaches this instance
+func (f *RedisFanout) Replay(request *ReplayRequest) {
+	f.ops.Push(func(ctx context.Context) {
+		replay, err := f.replay(ctx, request)
+		if err != nil {
+			// Answer anyway so the client is not held back; it is told to resync
+			f.exec(err)
+			replay = &Replay{ReplayRequest: request}
+		}
+		f.replyQueue.Push(replay)
+	})
+}
+
+func (f *RedisFanout) replay(ctx context.Context, request *ReplayRequest) (*Replay, error) {
+	from := resumePoint{}
+	if request.From != nil {
+		from = *request.From
+	}
+	result, err := replayScript.Run(ctx, f.client, roomStreamKeys(request.Room),
+		randomHex(8), f.replayTTL.Milliseconds(), from.Epoch, strconv.FormatUint(from.Seq, 10)).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	epoch, _ := result[0].(string)
+	seq, _ := result[1].(int64)
+	complete, _ := result[2].(int64)
+	entries, _ := result[3].([]interface{})
+	replay := &Replay{ReplayRequest: request, Epoch: epoch, Seq: uint64(seq), Complete: complete == 1}
+	for _, entry := range entries {
+		value, _ := entry.(string)
+		var delivery Delivery
+		if err := json.Unmarshal([]byte(value), &delivery); err != nil {
+			return nil, err
+		}
+		replay.Missed = append(replay.Missed, &delivery)
+	}
+	return replay, nil
+}
+
+func (f *RedisFanout) Replays() <-chan *Replay {
+	return f.replays
+}
+
+func (f *RedisFanout) AddMember(room string, member Member) {
+	f.ops.Push(func(ctx context.Context) {
+		f.countMember(ctx, room, member, 1)
+	})
+}
+
+func (f *RedisFanout) RemoveMember(room string, member Member) {
+	f.ops.Push(func(ctx context.Context) {
+		f.countMember(ctx, room, member, -1)
+	})
+}
+
+// countMember records a change in this instance's connections for a member and
+// reports the member's connections over every instance on Presence
+func (f *RedisFanout) countMember(ctx context.Context, room string, member Member, delta int) {
+	local, ok := f.members[room]
+	if !ok {
+		local = make(map[string]*Member)
+		f.members[room] = local
+	}
+	present, ok := local[member.UserUUID]
+	if !ok {
+		present = &Member{UserUUID: member.UserUUID, FullName: member.FullName}
+		local[member.UserUUID] = present
+	}
+	present.Connections += delta
+	if present.Connections <= 0 {
+		delete(local, member.UserUUID)
+	}
+	if len(local) == 0 {
+		delete(f.members, room)
+	}
+
+	args := append(f.presenceArgs(room), member.UserUUID, member.FullName, delta)
+	result, err := presenceScript.Run(ctx, f.client, []string{presenceNodesKey(room)}, args...).Slice()
+	if err != nil {
+		f.exec(err)
+		return
+	}
+	connections, _ := result[0].(int64)
+	count, _ := result[1].(int64)
+	member.Connections = int(connections)
+	kind := presenceJoin
+	if delta < 0 {
+		kind = presenceLeave
+	}
+	f.updates.Push(&PresenceUpdate{Room: room, Kind: kind, Member: &member, Count: int(count)})
+}
+
+func (f *RedisFanout) ListMembers(query *PresenceUpdate) {
+	f.ops.Push(func(ctx context.Context) {
+		list, err := listPresenceScript.Run(ctx, f.client, []string{presenceNodesKey(query.Room)}, f.presenceArgs(query.Room)...).Slice()
+		if err != nil {
+			f.exec(err)
+		}
+		for i := 0; i+2 < len(list); i += 3 {
+			userUUID, _ := list[i].(string)
+			fullName, _ := list[i+1].(string)
+			connections, _ := list[i+2].(int64)
+			if connections <= 0 {
+				continue
+			}
+			query.Members = append(query.Members, &Member{UserUUID: userUUID, FullName: fullName, Connections: int(connections)})
+		}
+		query.Count = len(query.Members)
+		f.updates.Push(query)
+	})
+}
+
+func (f *RedisFanout) Presence() <-chan *PresenceUpdate {
+	return f.presence
+}
+
+func (f *RedisFanout) NodeID() string {
+	return f.nodeID
+}
+
+// ClaimSession runs in order with the other operations, after the joins queued before it
+func (f *RedisFanout) ClaimSession(ctx context.Context, room string) (bool, error) {
+	type claim struct {
+		owned bool
+		err   error
+	}
+	claimed := make(chan claim, 1)
+	f.ops.Push(func(opCtx context.Context) {
+		owned, err := claimSessionScript.Run(opCtx, f.client, []string{sessionOwnerKey(room)}, f.nodeID, nodeAliveKey("")).Bool()
+		if owned && ctx.Err() != nil {
+			// Nobody waits for the session anymore
+			f.exec(releaseSessionScript.Run(opCtx, f.client, []string{sessionOwnerKey(room)}, f.nodeID).Err())
+			owned = false
+		}
+		if owned {
+			f.sessions[room] = true
+		}
+		claimed <- claim{owned: owned, err: err}
+	})
+	select {
+	case result := <-claimed:
+		return result.owned, result.err
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (f *RedisFanout) ReleaseSession(room string) {
+	f.ops.Push(func(ctx context.Context) {
+		delete(f.sessions, room)
+		f.exec(releaseSessionScript.Run(ctx, f.client, []string{sessionOwnerKey(room)}, f.nodeID).Err())
+	})
+}
+
+func (f *RedisFanout) ForwardSession(call *SessionCall) {
+	f.ops.Push(func(ctx context.Context) {
+		payload, err := json.Marshal(&Delivery{Call: call})
+		if err != nil {
+			f.exec(err)
+			return
+		}
+		f.exec(forwardSessionScript.Run(ctx, f.client, []string{sessionOwnerKey(call.Room)},
+			f.nodeID, nodeAliveKey(""), f.nodeChannel(""), payload).Err())
+	})
+}
+
+func (f *RedisFanout) Send(nodeID string, delivery *Delivery) {
+	f.ops.Push(func(ctx context.Context) {
+		payload, err := json.Marshal(delivery)
+		if err != nil {
+			f.exec(err)
+			return
+		}
+		f.exec(f.client.Publish(ctx, f.nodeChannel(nodeID), payload).Err())
+	})
+}
+
+// Close takes this instance out of every room and user set, and its connections out
+// of every room's presence, gives up its sessions and stops receiving. When ctx ends first, the operations
+// still queued are abandoned.
+func (f *RedisFanout) Close(ctx context.Context) error {
+	f.ops.Push(func(ctx context.Context) {
+		pipe := f.client.Pipeline()
+		for room := range f.members {
+			pipe.Del(ctx, presenceKeys(room, f.nodeID)...)
+			pipe.SRem(ctx, presenceNodesKey(room), f.nodeID)
+		}
+		pipe.Del(ctx, nodeAliveKey(f.nodeID))
+		for room := range f.sessions {
+			releaseSessionScript.Run(ctx, pipe, []string{sessionOwnerKey(room)}, f.nodeID)
+		}
+		for room := range f.rooms {
+			pipe.SRem(ctx, roomNodesKey(room), f.nodeID)
+		}
+		for user := range f.users {
+			pipe.SRem(ctx, userNodesKey(user), f.nodeID)
+		}
+		_, err := pipe.Exec(ctx)
+		f.exec(err)
+	})
+	f.ops.Close()
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		// Fail the running operation and the rest of the backlog fast
+		f.cancel()
+		<-f.done
+	}
+	f.cancel()
+	f.replyQueue.Close()
+	f.updates.Close()
+	return f.pubsub.Close()
+}
+
+// process runs the queued Redis operations in order, so a publish never overtakes the join before it
+func (f *RedisFanout) process() {
+	defer close(f.done)
+	for {
+		op, ok := f.ops.Pop()
+		if !ok {
+			return
+		}
+		ctx, cancel := context.WithTimeout(f.ctx, 5*time.Second)
+		op(ctx)
+		cancel()
+	}
+}
+
+// heartbeat keeps this instance's key alive until the fanout closes. The refresh runs
+// in order with the other operations, so it can restore what the others dropped.
+func (f *RedisFanout) heartbeat() {
+	ticker := time.NewTicker(nodeTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			f.ops.Push(f.refresh)
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// refresh extends this instance's key. When it had expired, other instances may have
+// dropped this instance's presence and taken it out of its rooms, so all of it is
+// written back.
+func (f *RedisFanout) refresh(ctx context.Context) {
+	alive, err := f.client.SetXX(ctx, nodeAliveKey(f.nodeID), 1, nodeTTL).Result()
+	if err != nil || alive {
+		f.exec(err)
+		return
+	}
+
+	log.Println("Fanout heartbeat had lapsed, restoring this instance's rooms and presence")
+	pipe := f.client.TxPipeline()
+	pipe.Set(ctx, nodeAliveKey(f.nodeID), 1, nodeTTL)
+	for room := range f.rooms {
+		pipe.SAdd(ctx, roomNodesKey(room), f.nodeID)
+	}
+	for user := range f.users {
+		pipe.SAdd(ctx, userNodesKey(user), f.nodeID)
+	}
+	for room, members := range f.members {
+		keys := presenceKeys(room, f.nodeID)
+		pipe.Del(ctx, keys...)
+		for _, member := range members {
+			pipe.HSet(ctx, keys[0], member.UserUUID, member.Connections)
+			pipe.HSet(ctx, keys[1], member.UserUUID, member.FullName)
+		}
+		pipe.SAdd(ctx, presenceNodesKey(room), f.nodeID)
+	}
+	_, err = pipe.Exec(ctx)
+	f.exec(err)
+}
+
+func (f *RedisFanout) receive() {
+	defer close(f.deliveries)
+	for message := range f.pubsub.Channel() {
+		var delivery Delivery
+		if err := json.Unmarshal([]byte(message.Payload), &delivery); err != nil {
+			log.Println("Fanout decode error:", err)
+			continue
+		}
+		f.deliveries <- &delivery
+	}
+}
+
+func (f *RedisFanout) exec(err error) {
+	if err != nil && err != redis.Nil && f.ctx.Err() == nil {
+		log.Println("Fanout error:", err)
+	}
+}
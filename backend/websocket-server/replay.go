@@ -0,0 +1,191 @@
+// replay.go
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// A room's broadcasts form a stream. The fanout numbers each broadcast as it is
+// published and keeps the latest ones, so a client that reconnects, to this instance
+// or another, can catch up. The epoch identifies the numbering: the stream outlives
+// the room's connections and is only recreated once nothing has been published or
+// replayed in it for the replay TTL. A client holding another epoch has to resync.
+
+// ReplayBuffer is a room's stream kept in memory, for MemoryFanout
+type ReplayBuffer struct {
+	epoch    string
+	seq      uint64
+	entries  []*Delivery // Ring of the last len(entries) broadcasts
+	start    int
+	size     int
+	lastUsed time.Time
+}
+
+func NewReplayBuffer(capacity int) *ReplayBuffer {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &ReplayBuffer{
+		epoch:   randomHex(8),
+		entries: make([]*Delivery, capacity),
+	}
+}
+
+// Append numbers a room delivery and keeps it
+func (b *ReplayBuffer) Append(delivery *Delivery) {
+	b.seq++
+	delivery.Epoch = b.epoch
+	delivery.Seq = b.seq
+
+	end := (b.start + b.size) % len(b.entries)
+	b.entries[end] = delivery
+	if b.size < len(b.entries) {
+		b.size++
+	} else {
+		b.start = (b.start + 1) % len(b.entries)
+	}
+}
+
+// Since returns the broadcasts after seq in order, or false when some of them
+// are no longer kept or seq does not belong to this epoch
+func (b *ReplayBuffer) Since(epoch string, seq uint64) ([]*Delivery, bool) {
+	if epoch != b.epoch || seq > b.seq {
+		return nil, false
+	}
+	missed := int(b.seq - seq)
+	if missed > b.size {
+		return nil, false
+	}
+
+	deliveries := make([]*Delivery, 0, missed)
+	for i := b.size - missed; i < b.size; i++ {
+		deliveries = append(deliveries, b.entries[(b.start+i)%len(b.entries)])
+	}
+	return deliveries, true
+}
+
+// resumePoint is the last broadcast a reconnecting client received
+type resumePoint struct {
+	Epoch string `json:"epoch"`
+	Seq   uint64 `json:"seq"`
+}
+
+// precedes reports whether delivery comes after this point. A delivery from another
+// epoch starts a new stream and always does.
+func (p resumePoint) precedes(delivery *Delivery) bool {
+	return delivery.Epoch != p.Epoch || delivery.Seq > p.Seq
+}
+
+// ReplayRequest asks the fanout where a room's stream stands and, for a client that
+// resumes, for the broadcasts it missed since From
+type ReplayRequest struct {
+	Room string
+	From *resumePoint
+	join *roomRequest // The join the replay answers; the fanout passes it through
+}
+
+// Replay answers a ReplayRequest on the fanout's Replays channel
+type Replay struct {
+	*ReplayRequest
+	Epoch    string
+	Seq      uint64      // Last broadcast so far; the next one carries seq+1
+	Missed   []*Delivery // Broadcasts after From, oldest first
+	Complete bool        // False when some of them are no longer kept or From is from another epoch
+}
+
+// sequencedFrame stamps a room delivery's sequence number on its envelope
+func sequencedFrame(delivery *Delivery) (*Frame, error) {
+	var envelope Envelope
+	if err := json.Unmarshal(delivery.Data, &envelope); err != nil {
+		return nil, err
+	}
+	envelope.Seq = delivery.Seq
+	stamped, err := json.Marshal(&envelope)
+	if err != nil {
+		return nil, err
+	}
+	return NewFrame(envelope.Type, stamped), nil
+}
+
+// heldBroadcast is a room broadcast kept back from a client until its join is answered
+type heldBroadcast struct {
+	delivery *Delivery
+	frame    *Frame
+}
+
+type joinReply struct {
+	Room           string `json:"room"`
+	Epoch          string `json:"epoch"`
+	Seq            uint64 `json:"seq"` // Last broadcast so far; the next one carries seq+1
+	Replayed       int    `json:"replayed,omitempty"`
+	ResyncRequired bool   `json:"resync_required,omitempty"`
+}
+
+// handleRoomRequest joins a client to a room and asks the fanout where the room's
+// stream stands. Until the answer arrives the room's broadcasts are held back from
+// the client, so nothing is lost or sent twice around the join.
+func (s *Server) handleRoomRequest(request *roomRequest) {
+	client := request.client
+	s.joinRoom(client, request.room)
+	room := client.room
+	if room == nil || room.name != request.room {
+		return
+	}
+
+	client.joining = request
+	client.held = nil
+	s.fanout.Replay(&ReplayRequest{Room: room.name, From: request.resume, join: request})
+}
+
+// handleReplay answers a join. A resuming client first gets what it missed, which its
+// queue never discards; without the missed broadcasts the reply asks it to resync, for
+// example by requesting session.state. The broadcasts held back during the lookup
+// follow the reply.
+func (s *Server) handleReplay(replay *Replay) {
+	request := replay.join
+	client := request.client
+	if _, ok := s.clients[client]; !ok || client.joining != request {
+		return
+	}
+	held := client.held
+	client.joining, client.held = nil, nil
+
+	reply := joinReply{Room: replay.Room, Epoch: replay.Epoch, Seq: replay.Seq}
+	if request.resume != nil {
+		reply.ResyncRequired = !replay.Complete
+		reply.Replayed = len(replay.Missed)
+		if replay.Complete {
+			client.stream = *request.resume
+		}
+		for _, delivery := range replay.Missed {
+			frame, err := sequencedFrame(delivery)
+			if err != nil {
+				continue
+			}
+			if !client.queueReplayed(delivery, frame) {
+				s.removeClient(client)
+				return
+			}
+		}
+	}
+	client.stream = resumePoint{Epoch: replay.Epoch, Seq: replay.Seq}
+	s.sendEnvelope(client, TypeReply, request.requestID, reply)
+
+	for _, broadcast := range held {
+		if !client.queueBroadcast(broadcast.delivery, broadcast.frame) {
+			s.removeClient(client)
+			return
+		}
+	}
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(buf)
+}
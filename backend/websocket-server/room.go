@@ -3,7 +3,9 @@ package main
 
 type Room struct {
 	name    string
-	clients map[*Client]bool
+	clients map[*Client]bool // Connections on this instance; the fanout counts members over every instance
+	session *LiveSession     // Live quiz session hosted in the room, if any
+	live    *SessionSnapshot // Latest state of the room's session, wherever it runs
 }
 
 func NewRoom(name string) *Room {
@@ -21,10 +23,48 @@ func (r *Room) Join(client *Client) {
 func (r *Room) Leave(client *Client) {
 	delete(r.clients, client)
 	client.room = nil
+	client.stream = resumePoint{}
+	client.joining, client.held = nil, nil
 }
 
-func (r *Room) Broadcast(message []byte) {
+func (r *Room) Empty() bool {
+	return len(r.clients) == 0
+}
+
+// Broadcast queues a room delivery for every member without blocking and returns
+// the members that the slow-consumer policy says to disconnect
+func (r *Room) Broadcast(delivery *Delivery, frame *Frame) []*Client {
+	var slow []*Client
 	for client := range r.clients {
-		client.send <- message
+		if !client.queueBroadcast(delivery, frame) {
+			slow = append(slow, client)
+		}
+	}
+	return slow
+}
+
+// queueBroadcast queues a room broadcast the client does not have yet, or holds it
+// back while the client's join is being answered. It reports false when the
+// slow-consumer policy says to disconnect.
+func (c *Client) queueBroadcast(delivery *Delivery, frame *Frame) bool {
+	if c.joining != nil {
+		c.held = append(c.held, heldBroadcast{delivery: delivery, frame: frame})
+		return true
+	}
+	if !c.stream.precedes(delivery) {
+		return true
+	}
+	c.stream = resumePoint{Epoch: delivery.Epoch, Seq: delivery.Seq}
+	return c.queue.Push(frame)
+}
+
+// queueReplayed queues a broadcast the client missed while disconnected. Once the
+// join reply tells the client it caught up, none of them may be discarded, so the
+// slow-consumer policy can only disconnect it.
+func (c *Client) queueReplayed(delivery *Delivery, frame *Frame) bool {
+	if !c.stream.precedes(delivery) {
+		return true
 	}
+	c.stream = resumePoint{Epoch: delivery.Epoch, Seq: delivery.Seq}
+	return c.queue.PushReliable(frame)
 }
@@ -1,43 +1,367 @@
 // server.go
 package main
 
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Message is an encoded envelope on its way to a client, or to the client's room
+type Message struct {
+	client    *Client
+	msgType   string
+	data      []byte
+	requestID string
+}
+
+// roomRequest asks the hub to move a client into a room, optionally resuming its stream
+type roomRequest struct {
+	client    *Client
+	room      string
+	requestID string
+	resume    *resumePoint
+}
+
 type Server struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	conns      map[string]*Client // Clients by id
+	broadcast  chan *Message
+	direct     chan *Message
 	register   chan *Client
 	unregister chan *Client
+	join       chan *roomRequest
+	leave      chan *Client
+	sessions   chan *sessionCommand
+	presence   chan *Message
+	stats      chan chan []ClientStats
+	drain      chan chan struct{}
+	drainDone  chan struct{}
+	forceClose chan struct{}
+	draining   atomic.Bool
+	timeUps    chan *timeUp
 	rooms      map[string]*Room
+	users      map[string]map[*Client]bool
+	fanout     Fanout
+
+	auth                 *Authenticator
+	dispatcher           *Dispatcher
+	quizzes              QuizSource
+	upgrader             websocket.Upgrader
+	sessionCheckInterval time.Duration
+	answerGrace          time.Duration
+	sendQueueSize        int
+	policies             map[string]Policy
+	reconnectJitter      time.Duration
+	compressionThreshold int
 }
 
-func NewServer() *Server {
+func NewServer(cfg *Config, auth *Authenticator, quizzes QuizSource, fanout Fanout) *Server {
+	dispatcher := NewDispatcher()
+	registerHandlers(dispatcher)
+
 	return &Server{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		rooms:      make(map[string]*Room),
+		auth:       auth,
+		dispatcher: dispatcher,
+		quizzes:    quizzes,
+		fanout:     fanout,
+		upgrader: websocket.Upgrader{
+			CheckOrigin:       originChecker(cfg.AllowedOrigins),
+			Subprotocols:      []string{SubprotocolMsgpack, SubprotocolJSON, SubprotocolLegacy},
+			EnableCompression: cfg.Compression,
+		},
+		sessionCheckInterval: cfg.SessionCheckInterval,
+		answerGrace:          cfg.AnswerGrace,
+		sendQueueSize:        cfg.SendQueueSize,
+		policies:             cfg.Policies,
+		reconnectJitter:      cfg.ReconnectJitter,
+		compressionThreshold: cfg.CompressionThreshold,
+		clients:              make(map[*Client]bool),
+		conns:                make(map[string]*Client),
+		broadcast:            make(chan *Message),
+		direct:               make(chan *Message),
+		register:             make(chan *Client),
+		unregister:           make(chan *Client),
+		join:                 make(chan *roomRequest),
+		leave:                make(chan *Client),
+		sessions:             make(chan *sessionCommand),
+		presence:             make(chan *Message),
+		stats:                make(chan chan []ClientStats),
+		drain:                make(chan chan struct{}),
+		forceClose:           make(chan struct{}),
+		timeUps:              make(chan *timeUp),
+		rooms:                make(map[string]*Room),
+		users:                make(map[string]map[*Client]bool),
 	}
 }
 
+// originChecker allows the configured origins; without any, gorilla's same-origin check applies
+func originChecker(allowed []string) func(r *http.Request) bool {
+	if len(allowed) == 0 {
+		return nil
+	}
+	origins := make(map[string]bool, len(allowed))
+	for _, origin := range allowed {
+		if origin == "*" {
+			return func(r *http.Request) bool { return true }
+		}
+		origins[origin] = true
+	}
+	return func(r *http.Request) bool {
+		return origins[r.Header.Get("Origin")]
+	}
+}
+
+// Run owns clients and rooms; every change to them goes through its channels
 func (s *Server) Run() {
+	deliveries := s.fanout.Deliveries()
+	replays := s.fanout.Replays()
+	presence := s.fanout.Presence()
 	for {
 		select {
 		case client := <-s.register:
-			s.clients[client] = true
+			s.addClient(client)
 		case client := <-s.unregister:
-			if _, ok := s.clients[client]; ok {
-				delete(s.clients, client)
-				close(client.send)
-			}
+			s.removeClient(client)
+		case request := <-s.join:
+			s.handleRoomRequest(request)
+		case client := <-s.leave:
+			s.leaveRoom(client)
 		case message := <-s.broadcast:
-			for client := range s.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(s.clients, client)
-				}
+			s.relayRoomMessage(message)
+		case message := <-s.direct:
+			s.sendTo(message.client, NewFrame(message.msgType, message.data))
+		case message := <-s.presence:
+			s.listPresence(message)
+		case done := <-s.drain:
+			s.startDrain(done)
+		case <-s.forceClose:
+			s.closeRemaining()
+		case reply := <-s.stats:
+			reply <- s.clientStats()
+		case command := <-s.sessions:
+			s.handleSessionCommand(command)
+		case event := <-s.timeUps:
+			s.handleTimeUp(event)
+		case delivery, ok := <-deliveries:
+			if !ok {
+				deliveries = nil
+				continue
 			}
+			s.deliver(delivery)
+		case replay, ok := <-replays:
+			if !ok {
+				replays = nil
+				continue
+			}
+			s.handleReplay(replay)
+		case update, ok := <-presence:
+			if !ok {
+				presence = nil
+				continue
+			}
+			s.handlePresenceUpdate(update)
+		}
+	}
+}
+
+func (s *Server) joinRoom(client *Client, name string) {
+	if _, ok := s.clients[client]; !ok {
+		return
+	}
+	if client.room != nil && client.room.name == name {
+		return
+	}
+	s.leaveRoom(client)
+
+	room, ok := s.rooms[name]
+	if !ok {
+		room = NewRoom(name)
+		s.rooms[name] = room
+		s.fanout.JoinRoom(name)
+	}
+	room.Join(client)
+	s.fanout.AddMember(name, client.member())
+	if room.session != nil {
+		room.session.AddPlayer(client.userUUID, client.fullName)
+		s.sendEnvelope(client, sessionState, "", room.session.Snapshot())
+	} else {
+		// The room's session may run on another instance
+		s.fanout.ForwardSession(s.sessionCall(client, sessionJoin))
+	}
+}
+
+// leaveRoom takes a client out of its room and drops the room once it is empty
+func (s *Server) leaveRoom(client *Client) {
+	room := client.room
+	if room == nil {
+		return
+	}
+	room.Leave(client)
+	s.fanout.RemoveMember(room.name, client.member())
+	// A running session stays while players on other instances remain; the room goes
+	// once its presence, counted over every instance, drops to nobody
+	if room.Empty() && (room.session == nil || room.session.state == StateFinished) {
+		s.dropRoom(room)
+	}
+}
+
+// dropRoom forgets an empty room, along with its session
+func (s *Server) dropRoom(room *Room) {
+	if room.session != nil {
+		stopTimer(room.session.currentRound())
+		s.fanout.ReleaseSession(room.name)
+	}
+	delete(s.rooms, room.name)
+	s.fanout.LeaveRoom(room.name)
+}
+
+// relayRoomMessage publishes a client's message to its room. While a question of the
+// room's session is open only the host may post, so players cannot pass answers around.
+func (s *Server) relayRoomMessage(message *Message) {
+	client := message.client
+	room := client.room
+	if room == nil {
+		s.sendError(client, message.requestID, NewProtocolError(ErrCodeBadRequest, "join a room first"))
+		return
+	}
+	if live := room.live; live != nil && live.State == StateQuestion && client.userUUID != live.HostUUID {
+		s.sendError(client, message.requestID, ErrQuestionOpen)
+		return
+	}
+	s.publishRoom(room.name, message.data)
+	if message.requestID != "" {
+		s.sendEnvelope(client, TypeReply, message.requestID, struct{}{})
+	}
+}
+
+// publishRoom sends a message to a room's clients on every instance, this one included
+func (s *Server) publishRoom(room string, message []byte) {
+	s.fanout.Publish(&Delivery{Room: room, Data: message})
+}
+
+// publishUser sends a message to every connection of a user, on whichever instance it is
+func (s *Server) publishUser(userUUID string, message []byte) {
+	s.fanout.Publish(&Delivery{UserUUID: userUUID, Data: message})
+}
+
+// deliver hands a published message to the local clients it is meant for, or runs
+// the session command it carries
+func (s *Server) deliver(delivery *Delivery) {
+	switch {
+	case delivery.Call != nil:
+		s.runSessionCall(delivery.Call)
+	case delivery.Room != "":
+		room, ok := s.rooms[delivery.Room]
+		if !ok {
+			return
+		}
+		frame, err := sequencedFrame(delivery)
+		if err != nil {
+			log.Println("Broadcast error:", err)
+			return
+		}
+		if frame.msgType == sessionState {
+			observeSession(room, delivery.Data)
+		}
+		s.broadcastToRoom(room, delivery, frame)
+		if room.Empty() && frame.msgType == presenceLeave {
+			s.dropAbandoned(room, delivery)
+		}
+	case delivery.Conn != "":
+		client, ok := s.conns[delivery.Conn]
+		if !ok {
+			return
+		}
+		msgType := envelopeType(delivery.Data)
+		// The state of the session sent to a player joining on this instance
+		if msgType == sessionState && client.room != nil {
+			observeSession(client.room, delivery.Data)
+		}
+		s.sendTo(client, NewFrame(msgType, delivery.Data))
+	default:
+		frame := NewFrame(envelopeType(delivery.Data), delivery.Data)
+		for client := range s.users[delivery.UserUUID] {
+			s.sendTo(client, frame)
+		}
+	}
+}
+
+// dropAbandoned drops a room kept for its session once the last user left it on every instance
+func (s *Server) dropAbandoned(room *Room, delivery *Delivery) {
+	var envelope Envelope
+	var event presenceEvent
+	if json.Unmarshal(delivery.Data, &envelope) != nil || envelope.Decode(&event) != nil {
+		return
+	}
+	if event.Count == 0 {
+		s.dropRoom(room)
+	}
+}
+
+func (s *Server) broadcastToRoom(room *Room, delivery *Delivery, frame *Frame) {
+	for _, client := range room.Broadcast(delivery, frame) {
+		s.removeClient(client)
+	}
+}
+
+// sendTo queues a message for one client, disconnecting it if the slow-consumer policy says so
+func (s *Server) sendTo(client *Client, frame *Frame) {
+	if _, ok := s.clients[client]; !ok {
+		return
+	}
+	if !client.queue.Push(frame) {
+		s.removeClient(client)
+	}
+}
+
+func (s *Server) sendEnvelope(client *Client, msgType, requestID string, payload interface{}) {
+	data, err := EncodeEnvelope(msgType, requestID, payload)
+	if err != nil {
+		return
+	}
+	s.sendTo(client, NewFrame(msgType, data))
+}
+
+func (s *Server) sendError(client *Client, requestID string, err *ProtocolError) {
+	s.sendEnvelope(client, TypeError, requestID, err)
+}
+
+func (s *Server) addClient(client *Client) {
+	s.clients[client] = true
+	s.conns[client.id] = client
+	connections, ok := s.users[client.userUUID]
+	if !ok {
+		connections = make(map[*Client]bool)
+		s.users[client.userUUID] = connections
+		s.fanout.AddUser(client.userUUID)
+	}
+	connections[client] = true
+
+	// Upgrades that raced the start of a shutdown are turned away at once
+	if s.draining.Load() {
+		s.goAway(client)
+	}
+}
+
+func (s *Server) removeClient(client *Client) {
+	if _, ok := s.clients[client]; !ok {
+		return
+	}
+	s.leaveRoom(client)
+	delete(s.clients, client)
+	delete(s.conns, client.id)
+	if connections := s.users[client.userUUID]; connections != nil {
+		delete(connections, client)
+		if len(connections) == 0 {
+			delete(s.users, client.userUUID)
+			s.fanout.RemoveUser(client.userUUID)
 		}
 	}
+	client.queue.Close()
+	s.checkDrained()
 }
@@ -0,0 +1,252 @@
+// session.go
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// SessionState is a stage of a live quiz session
+type SessionState string
+
+const (
+	StateLobby       SessionState = "lobby"
+	StateQuestion    SessionState = "question"
+	StateReveal      SessionState = "reveal"
+	StateLeaderboard SessionState = "leaderboard"
+	StateFinished    SessionState = "finished"
+)
+
+var (
+	ErrInvalidState    = NewProtocolError("invalid_state", "not possible at this stage of the session")
+	ErrWrongQuestion   = NewProtocolError("wrong_question", "this question is not open")
+	ErrAlreadyAnswered = NewProtocolError("already_answered", "the question was already answered")
+	ErrLateAnswer      = NewProtocolError("late_answer", "the time limit of the question has passed")
+	ErrHostAnswer      = NewProtocolError("host_answer", "the host does not play")
+)
+
+// PlayerAnswer is one player's answer to one round
+type PlayerAnswer struct {
+	UserUUID   string    `json:"user_uuid"`
+	Answers    string    `json:"answers"`
+	Correct    bool      `json:"correct"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// Round collects the answers given to one question
+type Round struct {
+	Question  *LiveQuestion
+	StartedAt time.Time
+	Deadline  time.Time // Zero when the question has no time limit
+	Skipped   bool
+	TimedOut  bool
+	Answers   map[string]*PlayerAnswer
+
+	timer *time.Timer
+}
+
+// PlayerScore is a leaderboard entry
+type PlayerScore struct {
+	UserUUID string `json:"user_uuid"`
+	FullName string `json:"fullname"`
+	Score    int    `json:"score"`
+	Correct  int    `json:"correct"`
+}
+
+// LiveSession is a host-driven run of a quiz in a room:
+// lobby -> (question -> reveal -> leaderboard)* -> finished.
+// It is owned by the hub goroutine and is not safe for concurrent use.
+type LiveSession struct {
+	quiz     *LiveQuiz
+	hostUUID string
+	state    SessionState
+	rounds   []*Round
+	scores   map[string]*PlayerScore
+	grace    time.Duration // Answers arriving this long after the deadline still count
+}
+
+func NewLiveSession(quiz *LiveQuiz, hostUUID string, grace time.Duration) *LiveSession {
+	return &LiveSession{
+		quiz:     quiz,
+		hostUUID: hostUUID,
+		state:    StateLobby,
+		scores:   make(map[string]*PlayerScore),
+		grace:    grace,
+	}
+}
+
+// AddPlayer puts a player on the leaderboard; the host does not play
+func (s *LiveSession) AddPlayer(userUUID, fullName string) {
+	if userUUID == s.hostUUID {
+		return
+	}
+	if _, ok := s.scores[userUUID]; !ok {
+		s.scores[userUUID] = &PlayerScore{UserUUID: userUUID, FullName: fullName}
+	}
+}
+
+// Start opens the first question
+func (s *LiveSession) Start(now time.Time) error {
+	if s.state != StateLobby {
+		return ErrInvalidState
+	}
+	s.openQuestion(0, now)
+	return nil
+}
+
+// Next moves to the following stage: question -> reveal -> leaderboard -> next question or finished
+func (s *LiveSession) Next(now time.Time) error {
+	switch s.state {
+	case StateQuestion:
+		s.reveal()
+	case StateReveal:
+		s.state = StateLeaderboard
+	case StateLeaderboard:
+		s.advance(now)
+	default:
+		return ErrInvalidState
+	}
+	return nil
+}
+
+// Skip closes the open question without scoring it and moves on
+func (s *LiveSession) Skip(now time.Time) error {
+	if s.state != StateQuestion {
+		return ErrInvalidState
+	}
+	s.currentRound().Skipped = true
+	s.advance(now)
+	return nil
+}
+
+// Answer records a player's answer to the open question; only the first answer counts.
+// receivedAt is the arrival time of the answer, checked against the deadline plus the grace window.
+func (s *LiveSession) Answer(userUUID, fullName, questionUUID string, answers []string, receivedAt time.Time) error {
+	if userUUID == s.hostUUID {
+		return ErrHostAnswer
+	}
+	if s.state != StateQuestion {
+		return ErrInvalidState
+	}
+	round := s.currentRound()
+	if round.Question.UUID != questionUUID {
+		return ErrWrongQuestion
+	}
+	if !round.Deadline.IsZero() && receivedAt.After(round.Deadline.Add(s.grace)) {
+		return ErrLateAnswer
+	}
+	if _, ok := round.Answers[userUUID]; ok {
+		return ErrAlreadyAnswered
+	}
+
+	s.AddPlayer(userUUID, fullName)
+	round.Answers[userUUID] = &PlayerAnswer{
+		UserUUID:   userUUID,
+		Answers:    normalizeAnswers(answers),
+		ReceivedAt: receivedAt,
+	}
+	return nil
+}
+
+func (s *LiveSession) openQuestion(index int, now time.Time) {
+	round := &Round{
+		Question:  s.quiz.Questions[index],
+		StartedAt: now,
+		Answers:   make(map[string]*PlayerAnswer),
+	}
+	if limit := round.Question.TimeLimit; limit > 0 {
+		round.Deadline = now.Add(time.Duration(limit) * time.Second)
+	}
+	s.rounds = append(s.rounds, round)
+	s.state = StateQuestion
+}
+
+func (s *LiveSession) advance(now time.Time) {
+	if next := len(s.rounds); next < len(s.quiz.Questions) {
+		s.openQuestion(next, now)
+		return
+	}
+	s.state = StateFinished
+}
+
+// reveal scores the open round
+func (s *LiveSession) reveal() {
+	round := s.currentRound()
+	for userUUID, answer := range round.Answers {
+		answer.Correct = answer.Answers == round.Question.CorrectAnswers
+		score, ok := s.scores[userUUID]
+		if !ok || !answer.Correct {
+			continue
+		}
+		score.Score += round.Question.Score
+		score.Correct++
+	}
+	s.state = StateReveal
+}
+
+func (s *LiveSession) currentRound() *Round {
+	if len(s.rounds) == 0 {
+		return nil
+	}
+	return s.rounds[len(s.rounds)-1]
+}
+
+// Leaderboard returns the players by score, ties broken by name
+func (s *LiveSession) Leaderboard() []PlayerScore {
+	leaderboard := make([]PlayerScore, 0, len(s.scores))
+	for _, score := range s.scores {
+		leaderboard = append(leaderboard, *score)
+	}
+	sort.Slice(leaderboard, func(i, j int) bool {
+		if leaderboard[i].Score != leaderboard[j].Score {
+			return leaderboard[i].Score > leaderboard[j].Score
+		}
+		return leaderboard[i].FullName < leaderboard[j].FullName
+	})
+	return leaderboard
+}
+
+// SessionSnapshot is what players see of a session; correct answers only appear once revealed
+type SessionSnapshot struct {
+	QuizUUID       string        `json:"quiz_uuid"`
+	Title          string        `json:"title"`
+	HostUUID       string        `json:"host_uuid"`
+	State          SessionState  `json:"state"`
+	QuestionIndex  int           `json:"question_index"`
+	TotalQuestions int           `json:"total_questions"`
+	Question       *LiveQuestion `json:"question,omitempty"`
+	Deadline       int64         `json:"deadline,omitempty"` // Unix milliseconds; compare with the envelope ts to sync countdowns
+	TimedOut       bool          `json:"timed_out,omitempty"`
+	AnswerCount    int           `json:"answer_count"`
+	CorrectAnswers []string      `json:"correct_answers,omitempty"`
+	Leaderboard    []PlayerScore `json:"leaderboard,omitempty"`
+}
+
+func (s *LiveSession) Snapshot() *SessionSnapshot {
+	snapshot := &SessionSnapshot{
+		QuizUUID:       s.quiz.UUID,
+		Title:          s.quiz.Title,
+		HostUUID:       s.hostUUID,
+		State:          s.state,
+		QuestionIndex:  len(s.rounds) - 1,
+		TotalQuestions: len(s.quiz.Questions),
+	}
+
+	round := s.currentRound()
+	switch s.state {
+	case StateQuestion:
+		snapshot.Question = round.Question
+		snapshot.TimedOut = round.TimedOut
+		snapshot.AnswerCount = len(round.Answers)
+		if !round.Deadline.IsZero() {
+			snapshot.Deadline = round.Deadline.UnixMilli()
+		}
+	case StateReveal:
+		snapshot.Question = round.Question
+		snapshot.AnswerCount = len(round.Answers)
+		snapshot.CorrectAnswers = splitList(round.Question.CorrectAnswers)
+	case StateLeaderboard, StateFinished:
+		snapshot.Leaderboard = s.Leaderboard()
+	}
+	return snapshot
+}
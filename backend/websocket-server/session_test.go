@@ -0,0 +1,133 @@
+// session_test.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestSession() *LiveSession {
+	quiz := &LiveQuiz{
+		UUID:  "quiz",
+		Title: "Vocabulary",
+		Questions: []*LiveQuestion{
+			{UUID: "q1", TimeLimit: 10, CorrectAnswers: "a", Score: 10},
+			{UUID: "q2", TimeLimit: 10, CorrectAnswers: "b,c", Score: 20},
+		},
+	}
+	session := NewLiveSession(quiz, "host", 500*time.Millisecond)
+	session.AddPlayer("host", "Host")
+	session.AddPlayer("alice", "Alice")
+	session.AddPlayer("bob", "Bob")
+	return session
+}
+
+func expectState(t *testing.T, session *LiveSession, state SessionState) {
+	t.Helper()
+	if session.state != state {
+		t.Fatalf("state = %s, want %s", session.state, state)
+	}
+}
+
+func TestSessionFlow(t *testing.T) {
+	session := newTestSession()
+	now := time.Now()
+
+	if err := session.Next(now); err != ErrInvalidState {
+		t.Fatalf("Next in lobby = %v, want ErrInvalidState", err)
+	}
+	if err := session.Start(now); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, session, StateQuestion)
+	if err := session.Start(now); err != ErrInvalidState {
+		t.Fatalf("second Start = %v, want ErrInvalidState", err)
+	}
+
+	if err := session.Answer("alice", "Alice", "q1", []string{"a"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Answer("bob", "Bob", "q1", []string{"b"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Next(now); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, session, StateReveal)
+	if err := session.Next(now); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, session, StateLeaderboard)
+
+	leaderboard := session.Leaderboard()
+	if len(leaderboard) != 2 || leaderboard[0].UserUUID != "alice" || leaderboard[0].Score != 10 || leaderboard[1].Score != 0 {
+		t.Fatalf("leaderboard = %+v", leaderboard)
+	}
+
+	if err := session.Next(now); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, session, StateQuestion)
+	if err := session.Answer("alice", "Alice", "q1", []string{"a"}, now); err != ErrWrongQuestion {
+		t.Fatalf("answer to a closed question = %v, want ErrWrongQuestion", err)
+	}
+	if err := session.Skip(now); err != nil {
+		t.Fatal(err)
+	}
+	expectState(t, session, StateFinished)
+	if !session.rounds[1].Skipped {
+		t.Fatal("skipped round is not marked")
+	}
+	if err := session.Skip(now); err != ErrInvalidState {
+		t.Fatalf("Skip when finished = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestSessionLateAnswer(t *testing.T) {
+	session := newTestSession()
+	now := time.Now()
+	session.Start(now)
+
+	deadline := session.currentRound().Deadline
+	if err := session.Answer("alice", "Alice", "q1", []string{"a"}, deadline.Add(400*time.Millisecond)); err != nil {
+		t.Fatalf("answer within the grace window = %v", err)
+	}
+	if err := session.Answer("bob", "Bob", "q1", []string{"a"}, deadline.Add(time.Second)); err != ErrLateAnswer {
+		t.Fatalf("late answer = %v, want ErrLateAnswer", err)
+	}
+}
+
+func TestSessionRepeatedAnswer(t *testing.T) {
+	session := newTestSession()
+	now := time.Now()
+	session.Start(now)
+
+	if err := session.Answer("alice", "Alice", "q1", []string{"b"}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Answer("alice", "Alice", "q1", []string{"a"}, now); err != ErrAlreadyAnswered {
+		t.Fatalf("repeated answer = %v, want ErrAlreadyAnswered", err)
+	}
+	session.Next(now)
+	if session.scores["alice"].Score != 0 {
+		t.Fatal("the repeated answer replaced the first one")
+	}
+}
+
+func TestSessionHostAnswer(t *testing.T) {
+	session := newTestSession()
+	now := time.Now()
+	session.Start(now)
+
+	if err := session.Answer("host", "Host", "q1", []string{"a"}, now); err != ErrHostAnswer {
+		t.Fatalf("host answer = %v, want ErrHostAnswer", err)
+	}
+	// An answer without a leaderboard entry must not bring the hub down when scored
+	session.currentRound().Answers["ghost"] = &PlayerAnswer{UserUUID: "ghost", Answers: "a"}
+	if err := session.Next(now); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := session.scores["host"]; ok {
+		t.Fatal("the host is on the leaderboard")
+	}
+}
@@ -0,0 +1,106 @@
+// shutdown.go
+package main
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const serverGoingAway = "server.going_away"
+
+// forceCloseGrace is how long Shutdown waits for force-closed connections to unregister
+const forceCloseGrace = 2 * time.Second
+
+// goingAwayPayload tells a client to reconnect, after a random delay so a deploy does
+// not bring every client back at once, and where to resume from
+type goingAwayPayload struct {
+	Reason         string       `json:"reason"`
+	ReconnectAfter int64        `json:"reconnect_after_ms"`
+	Room           string       `json:"room,omitempty"`
+	Resume         *resumePoint `json:"resume,omitempty"`
+}
+
+// Shutdown stops accepting connections, asks every client to reconnect elsewhere and
+// waits until they are gone. When ctx ends first, the remaining connections are closed
+// with a going-away close frame and ctx's error is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.draining.Store(true)
+	done := make(chan struct{})
+	select {
+	case s.drain <- done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	s.forceClose <- struct{}{}
+	select {
+	case <-done:
+	case <-time.After(forceCloseGrace):
+	}
+	return ctx.Err()
+}
+
+// startDrain runs in the hub; done is closed once the last client has disconnected
+func (s *Server) startDrain(done chan struct{}) {
+	s.drainDone = done
+	for _, room := range s.rooms {
+		if room.session != nil {
+			stopTimer(room.session.currentRound())
+		}
+	}
+	for client := range s.clients {
+		s.goAway(client)
+	}
+	s.checkDrained()
+}
+
+// goAway sends the reconnect hint and closes the client's queue. The writePump
+// flushes what is queued, then sends a going-away close frame.
+func (s *Server) goAway(client *Client) {
+	payload := goingAwayPayload{Reason: "server shutting down"}
+	if s.reconnectJitter > 0 {
+		payload.ReconnectAfter = rand.Int63n(s.reconnectJitter.Milliseconds() + 1)
+	}
+	if room := client.room; room != nil {
+		payload.Room = room.name
+		// The stream is shared by every instance, so the client resumes wherever it reconnects
+		if client.stream.Epoch != "" {
+			resume := client.stream
+			payload.Resume = &resume
+		}
+	}
+	s.sendEnvelope(client, serverGoingAway, "", payload)
+	client.queue.CloseWith(websocket.CloseGoingAway, payload.Reason)
+}
+
+// closeRemaining runs in the hub once the drain deadline has passed. Closing a
+// connection ends its pumps, and the readPump unregisters the client.
+func (s *Server) closeRemaining() {
+	for client := range s.clients {
+		go client.closeGoingAway()
+	}
+}
+
+// closeGoingAway sends a going-away close frame, even while the writePump is blocked
+// on a slow client, and closes the connection
+func (c *Client) closeGoingAway() {
+	message := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(time.Second))
+	c.conn.Close()
+}
+
+func (s *Server) checkDrained() {
+	if s.drainDone != nil && len(s.clients) == 0 {
+		close(s.drainDone)
+		s.drainDone = nil
+	}
+}
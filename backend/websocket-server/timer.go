@@ -0,0 +1,47 @@
+// timer.go
+package main
+
+import (
+	"time"
+)
+
+// timeUp is delivered to the hub when a question's time limit runs out
+type timeUp struct {
+	room    *Room
+	session *LiveSession
+	round   *Round
+}
+
+// scheduleTimeUp arms the timer of a freshly opened round. Questions without a
+// time limit stay open until the host moves on.
+func (s *Server) scheduleTimeUp(room *Room, session *LiveSession) {
+	round := session.currentRound()
+	if session.state != StateQuestion || round.Deadline.IsZero() || round.timer != nil {
+		return
+	}
+	round.timer = time.AfterFunc(time.Until(round.Deadline), func() {
+		s.timeUps <- &timeUp{room: room, session: session, round: round}
+	})
+}
+
+// stopTimer disarms the timer of a round that closed before its time limit
+func stopTimer(round *Round) {
+	if round != nil && round.timer != nil {
+		round.timer.Stop()
+	}
+}
+
+// handleTimeUp closes the question window and tells the room. Timers of rounds that
+// were skipped, or of sessions that ended in the meantime, are ignored.
+func (s *Server) handleTimeUp(event *timeUp) {
+	session := event.session
+	if event.room.session != session || session.currentRound() != event.round || session.state != StateQuestion {
+		return
+	}
+	event.round.TimedOut = true
+	s.broadcastEnvelope(event.room, "session.time_up", map[string]interface{}{
+		"question_uuid": event.round.Question.UUID,
+		"deadline":      event.round.Deadline.UnixMilli(),
+		"answer_count":  len(event.round.Answers),
+	})
+}
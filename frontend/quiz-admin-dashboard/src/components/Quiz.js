@@ -13,7 +13,7 @@ import {
 } from "../services/questionService";
 import ModalQuestion from "./ModalQuestion";
 
-const Quiz = ({ quiz, onQuizUpdate, onQuizDelete, socketId, onShare }) => {
+const Quiz = ({ quiz, onQuizUpdate, onQuizDelete, onShare }) => {
   const [showUpdateModal, setShowUpdateModal] = useState(false);
   const [showDeleteModal, setShowDeleteModal] = useState(false);
   const [showQuestionModal, setShowQuestionModal] = useState(false);
@@ -61,9 +61,9 @@ const Quiz = ({ quiz, onQuizUpdate, onQuizDelete, socketId, onShare }) => {
       const updatedQuiz = { ...quiz, is_published: !quiz.is_published };
       await updateQuiz(quiz.uuid, updatedQuiz);
       if (updatedQuiz.is_published) {
-        quizPublish(quiz.uuid, socketId);
+        quizPublish(quiz.uuid);
       } else {
-        quizUnpublish(quiz.uuid, socketId);
+        quizUnpublish(quiz.uuid);
       }
       onQuizUpdate(updatedQuiz);
     } catch (error) {
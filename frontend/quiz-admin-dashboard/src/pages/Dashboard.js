@@ -11,7 +11,6 @@ import ModalQuiz from "../components/ModalQuiz";
 import ModalChangePassword from "../components/ModalChangePassword";
 import BootstrapToast from "../components/BootstrapToast";
 import { FaPlus, FaSignOutAlt, FaUserEdit, FaLock } from "react-icons/fa";
-import { io } from "socket.io-client";
 
 const Dashboard = () => {
   const [quizzes, setQuizzes] = useState([]);
@@ -19,7 +18,6 @@ const Dashboard = () => {
   const [totalPages, setTotalPages] = useState(1);
   const [showCreateModal, setShowCreateModal] = useState(false);
   const [showChangePasswordModal, setShowChangePasswordModal] = useState(false);
-  const [socketId, setSocketId] = useState("");
   const [toast, setToast] = useState({ show: false, message: "", type: "" });
   const [fullName, setFullName] = useState("");
   const [dropdownVisible, setDropdownVisible] = useState(false);
@@ -112,18 +110,23 @@ const Dashboard = () => {
     const storedFullName = localStorage.getItem("fullname") || "Guest User";
     setFullName(storedFullName);
 
+    // websocket-server delivers the result of a publish or unpublish to every
+    // connection of the user who requested it
     const userToken = localStorage.getItem("token");
-    const socket = io("http://127.0.0.1:8082", {
-      auth: { token: userToken },
-      transports: ["websocket", "polling"],
-    });
+    const socket = new WebSocket("ws://127.0.0.1:8083/ws", [
+      "quiz.json",
+      `bearer.${userToken}`,
+    ]);
 
-    socket.on("connect", () => setSocketId(socket.id));
-    socket.on("notification", (notification) =>
-      showToast(`Notification: ${notification.data}`, "info")
-    );
+    socket.onmessage = (message) => {
+      const event = JSON.parse(message.data);
+      if (event.type === "quiz.export" || event.type === "quiz.revoke") {
+        const { status, message: text } = event.payload || {};
+        showToast(text, status === "completed" ? "success" : "danger");
+      }
+    };
 
-    return () => socket.disconnect();
+    return () => socket.close();
   }, []);
 
   useEffect(() => {
@@ -188,7 +191,6 @@ const Dashboard = () => {
           <Quiz
             key={quiz.uuid}
             quiz={quiz}
-            socketId={socketId}
             onQuizUpdate={(updatedQuiz) => handleSaveQuiz(updatedQuiz)}
             onQuizDelete={(quizUuid) => handleDeleteQuiz(quizUuid)}
           />
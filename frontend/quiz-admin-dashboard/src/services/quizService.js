@@ -20,12 +20,12 @@ export const deleteQuiz = async (uuid) => {
   return response.data;
 };
 
-export const quizPublish = async (uuid, socketId) => {
-  const response = await api.get(`/quizzes/quiz-export/${uuid}?socket_id=${socketId}`);
+export const quizPublish = async (uuid) => {
+  const response = await api.get(`/quizzes/quiz-export/${uuid}`);
   return response.data;
 };
 
-export const quizUnpublish = async (uuid, socketId) => {
-  const response = await api.get(`/quizzes/revoke-quiz/${uuid}?socket_id=${socketId}`);
+export const quizUnpublish = async (uuid) => {
+  const response = await api.get(`/quizzes/revoke-quiz/${uuid}`);
   return response.data;
 };
@@ -54,6 +54,14 @@ const Login = () => {
           </div>
           <button type="submit" className="btn btn-primary w-100">Login</button>
         </form>
+        <div className="text-center mt-3">
+          <button
+            className="btn btn-link p-0"
+            onClick={() => navigate('/reset-password')}
+          >
+            Forgot password?
+          </button>
+        </div>
         <div className="text-center mt-3">
           <span>Don't have an account? </span>
           <button
@@ -7,6 +7,7 @@ const Register = () => {
   const [username, setUsername] = useState("");
   const [password, setPassword] = useState("");
   const [fullname, setFullname] = useState("");
+  const [email, setEmail] = useState("");
   const [errorMessage, setErrorMessage] = useState("");
   const [successMessage, setSuccessMessage] = useState("");
   const navigate = useNavigate();
@@ -14,11 +15,11 @@ const Register = () => {
   const handleSubmit = async (e) => {
     e.preventDefault();
     try {
-      const result = await register(username, password, fullname);
+      const result = await register(username, password, fullname, email);
       if (result.status === 201) {
         setErrorMessage("");
-        setSuccessMessage("Registration successful! Redirecting to login...");
-        setTimeout(() => navigate("/login"), 2000);
+        setSuccessMessage("Registration successful! Check your email to verify your address, then log in.");
+        setTimeout(() => navigate("/login"), 4000);
       } else {
         setSuccessMessage("");
         setErrorMessage(result.message || "Registration failed");
@@ -57,6 +58,19 @@ const Register = () => {
               required
             />
           </div>
+          <div className="mb-3">
+            <label htmlFor="email" className="form-label">
+              Email
+            </label>
+            <input
+              type="email"
+              id="email"
+              className="form-control"
+              value={email}
+              onChange={(e) => setEmail(e.target.value)}
+              required
+            />
+          </div>
           <div className="mb-3">
             <label htmlFor="username" className="form-label">
               Username
@@ -0,0 +1,76 @@
+import React, { useState } from "react";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import { forgotPassword, resetPassword } from "../services/authService";
+import "bootstrap/dist/css/bootstrap.min.css";
+
+// Without a token the page asks for a reset link; with one it sets the new password
+const ResetPassword = () => {
+  const [searchParams] = useSearchParams();
+  const token = searchParams.get("token");
+  const [email, setEmail] = useState("");
+  const [password, setPassword] = useState("");
+  const [result, setResult] = useState(null);
+  const navigate = useNavigate();
+
+  const handleSubmit = async (e) => {
+    e.preventDefault();
+    if (token) {
+      const response = await resetPassword(token, password);
+      setResult(response);
+      if (response.success) {
+        setTimeout(() => navigate("/login"), 2000);
+      }
+    } else {
+      setResult(await forgotPassword(email));
+    }
+  };
+
+  return (
+    <div className="d-flex justify-content-center align-items-center vh-100">
+      <div className="card p-4" style={{ width: "400px" }}>
+        <h3 className="text-center mb-4">Reset password</h3>
+        {result && (
+          <div className={`alert ${result.success ? "alert-success" : "alert-danger"}`} role="alert">
+            {result.message}
+          </div>
+        )}
+        <form onSubmit={handleSubmit}>
+          {token ? (
+            <div className="mb-3">
+              <label htmlFor="password" className="form-label">
+                New password
+              </label>
+              <input
+                type="password"
+                id="password"
+                className="form-control"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                required
+              />
+            </div>
+          ) : (
+            <div className="mb-3">
+              <label htmlFor="email" className="form-label">
+                Email
+              </label>
+              <input
+                type="email"
+                id="email"
+                className="form-control"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                required
+              />
+            </div>
+          )}
+          <button type="submit" className="btn btn-primary w-100">
+            {token ? "Set new password" : "Send reset link"}
+          </button>
+        </form>
+      </div>
+    </div>
+  );
+};
+
+export default ResetPassword;
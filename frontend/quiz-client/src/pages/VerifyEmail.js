@@ -0,0 +1,33 @@
+import React, { useEffect, useState } from "react";
+import { useNavigate, useSearchParams } from "react-router-dom";
+import { verifyEmail } from "../services/authService";
+import "bootstrap/dist/css/bootstrap.min.css";
+
+const VerifyEmail = () => {
+  const [searchParams] = useSearchParams();
+  const [result, setResult] = useState(null);
+  const navigate = useNavigate();
+
+  useEffect(() => {
+    verifyEmail(searchParams.get("token") || "").then(setResult);
+  }, [searchParams]);
+
+  return (
+    <div className="d-flex justify-content-center align-items-center vh-100">
+      <div className="card p-4" style={{ width: "400px" }}>
+        <h3 className="text-center mb-4">Email verification</h3>
+        {!result && <p className="text-center">Verifying...</p>}
+        {result && (
+          <div className={`alert ${result.success ? "alert-success" : "alert-danger"}`} role="alert">
+            {result.message}
+          </div>
+        )}
+        <button className="btn btn-primary w-100" onClick={() => navigate("/login")}>
+          Go to login
+        </button>
+      </div>
+    </div>
+  );
+};
+
+export default VerifyEmail;
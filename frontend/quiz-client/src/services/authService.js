@@ -47,9 +47,9 @@ export const changePassword = async (old_password, new_password) => {
   }
 };
 
-export const register = async (username, password, fullname) => {
+export const register = async (username, password, fullname, email) => {
   try {
-    const response = await api.post("/register", { username, password, fullname });
+    const response = await api.post("/register", { username, password, fullname, email });
     const { mesage } = response.data;
     return { mesage, status: response.status };
   } catch (error) {
@@ -59,3 +59,39 @@ export const register = async (username, password, fullname) => {
     };
   }
 };
+
+export const verifyEmail = async (token) => {
+  try {
+    const response = await api.post("/email/verify", { token });
+    return { success: true, message: response.data.message };
+  } catch (error) {
+    return {
+      success: false,
+      message: error.response?.data?.error || "Email verification failed",
+    };
+  }
+};
+
+export const forgotPassword = async (email) => {
+  try {
+    const response = await api.post("/password/forgot", { email });
+    return { success: true, message: response.data.message };
+  } catch (error) {
+    return {
+      success: false,
+      message: error.response?.data?.error || "Password reset request failed",
+    };
+  }
+};
+
+export const resetPassword = async (token, new_password) => {
+  try {
+    const response = await api.post("/password/reset", { token, new_password });
+    return { success: true, message: response.data.message };
+  } catch (error) {
+    return {
+      success: false,
+      message: error.response?.data?.error || "Password reset failed",
+    };
+  }
+};